@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"tuck/internal/cache"
+	"tuck/internal/log"
+	"tuck/internal/path"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheParams struct {
+	OlderThan string
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage downloaded assets",
+	Long: `Manage the cache of downloaded assets, assets are kept after installing
+so that reinstalling, rolling back or installing into another prefix does
+not download them again.`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List cached assets",
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := cache.List()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s %s %s (%s, used %s ago)\n", entry.Package, entry.Version,
+				entry.Name, formatSize(entry.Size), formatAge(time.Since(entry.Used)))
+			log.Infoln("  ", path.Contract(entry.Path()))
+		}
+	},
+}
+
+var cacheSizeCmd = &cobra.Command{
+	Use:   "size",
+	Args:  cobra.NoArgs,
+	Short: "Show the total size of cached assets",
+	Run: func(cmd *cobra.Command, args []string) {
+		size, err := cache.Size()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Println(formatSize(size))
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean [flags]",
+	Args:  cobra.NoArgs,
+	Short: "Remove cached assets",
+	Long: `Remove all cached assets, or with --older-than only those which have not
+been used within the given age, e.g. "12h", "30d" or "2w".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Debugf("cache clean: %+v\n", cacheParams)
+		olderThan := time.Duration(0)
+		if cacheParams.OlderThan != "" {
+			var err error
+			olderThan, err = parseAge(cacheParams.OlderThan)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		unlock, err := path.AcquireLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		removed, err := cache.Clean(olderThan)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		size := int64(0)
+		for _, entry := range removed {
+			log.Infoln("removed:", entry.Name)
+			size += entry.Size
+		}
+		fmt.Printf("tuck removed %d cached assets freeing %s\n", len(removed),
+			formatSize(size))
+	},
+}
+
+// parseAge parses a duration which may also use days or weeks as units.
+func parseAge(age string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	} {
+		if number, found := strings.CutSuffix(age, suffix); found {
+			count, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age: '%s'", age)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	duration, err := time.ParseDuration(age)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age: '%s'", age)
+	}
+	return duration, nil
+}
+
+func formatAge(age time.Duration) string {
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	}
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheSizeCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	cacheListCmd.Aliases = append(cacheListCmd.Aliases, "ls")
+	cacheCleanCmd.Flags().StringVar(&cacheParams.OlderThan, "older-than", "",
+		"only remove assets not used within this age")
+}
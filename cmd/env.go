@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"tuck/internal/config"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+var envParams struct {
+	Shell string
+}
+
+// envShells are the shells tuck env supports.
+var envShells = []string{"bash", "zsh", "fish", "nu", "pwsh"}
+
+// xdgDataDirs is the value of XDG_DATA_DIRS when it is not set.
+const xdgDataDirs = "/usr/local/share:/usr/share"
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Args:  cobra.NoArgs,
+	Short: "Print shell configuration for the install prefixes",
+	Long: `Print the shell commands adding the bin, man page and shared data
+directories of the prefixes to PATH, MANPATH and XDG_DATA_DIRS, and their
+completions to the shell, for the default prefix, every profile and any other
+prefix packages are installed into, or only the prefix selected with --profile
+or --prefix. The shell is taken from $SHELL unless given with --shell. Prefixes
+already configured are skipped so the commands are safe to run more than once.
+
+Add one of the following to the shell's startup file:
+
+  eval "$(tuck env)"                                  # bash, zsh
+  tuck env --shell fish | source                      # fish
+  tuck env --shell pwsh | Out-String | Invoke-Expression  # pwsh
+
+Nushell can't evaluate generated code, instead save it and source the file
+from config.nu:
+
+  tuck env --shell nu | save -f ~/.config/nushell/tuck.nu`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if envParams.Shell == "" {
+			envParams.Shell = detectShell()
+		}
+		log.Debugf("env: %+v\n", envParams)
+
+		// no lock is taken so that shells can start during an install, the
+		// state is replaced atomically so it is never read partially written
+		prefixes, err := envPrefixes()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		script, err := envScript(envParams.Shell, prefixes)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Print(script)
+	},
+}
+
+// detectShell returns the shell named by $SHELL, or bash when it is not one
+// of the supported shells.
+func detectShell() string {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if slices.Contains(envShells, shell) {
+		return shell
+	}
+	return "bash"
+}
+
+// envPrefixes returns the selected prefix, or the default prefix followed by
+// the prefixes of the profiles and the other prefixes packages are installed
+// into.
+func envPrefixes() ([]string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	profile, err := selectedProfile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	prefixes := []string{profile.Prefix}
+	if params.Profile != "" || params.Prefix != "" {
+		return prefixes, nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		profile, err := cfg.Profile(name)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefixPath(profile.Prefix))
+	}
+	pkgs, err := state.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(*pkgs) {
+		prefixes = append(prefixes, key.Prefix)
+	}
+
+	unique := []string{}
+	for _, prefix := range prefixes {
+		if !slices.Contains(unique, prefix) {
+			unique = append(unique, prefix)
+		}
+	}
+	return unique, nil
+}
+
+// envScript returns the commands configuring shell for the prefixes, the
+// first prefix takes precedence.
+func envScript(shell string, prefixes []string) (string, error) {
+	lines := []string{}
+	switch shell {
+	case "fish":
+		lines = append(lines,
+			"set -q MANPATH; or set -gx MANPATH ''",
+			"set -q XDG_DATA_DIRS; or set -gx XDG_DATA_DIRS "+fishQuote(xdgDataDirs),
+			// only names ending in PATH are split on colons without --path
+			"set -gx --path XDG_DATA_DIRS $XDG_DATA_DIRS",
+		)
+	case "pwsh":
+		lines = append(lines, fmt.Sprintf(
+			"if (-not $env:XDG_DATA_DIRS) { $env:XDG_DATA_DIRS = %s }", pwshQuote(xdgDataDirs)))
+	}
+	// each prefix is prepended so the first must be the last
+	for _, prefix := range slices.Backward(prefixes) {
+		bin := filepath.Join(prefix, "bin")
+		man := filepath.Join(prefix, "share", "man")
+		share := filepath.Join(prefix, "share")
+		switch shell {
+		case "bash", "zsh":
+			lines = append(lines,
+				posixPrepend("PATH", bin, `"${PATH:+:$PATH}"`),
+				// the empty entry keeps the default search path of man
+				posixPrepend("MANPATH", man, `:"${MANPATH-}"`),
+				// bash-completion finds completions in XDG_DATA_DIRS
+				posixPrepend("XDG_DATA_DIRS", share, `:"${XDG_DATA_DIRS:-`+xdgDataDirs+`}"`),
+			)
+			if shell == "zsh" {
+				lines = append(lines, fmt.Sprintf("typeset -U fpath; fpath=(%s $fpath)",
+					posixQuote(filepath.Join(share, "zsh", "site-functions"))))
+			}
+		case "fish":
+			lines = append(lines,
+				fmt.Sprintf("fish_add_path --global --path %s", fishQuote(bin)),
+				fishPrepend("-gx", "MANPATH", man),
+				fishPrepend("-gx", "XDG_DATA_DIRS", share),
+				// only fish reads it so it isn't exported
+				fishPrepend("-g", "fish_complete_path",
+					filepath.Join(share, "fish", "vendor_completions.d")),
+			)
+		case "nu":
+			lines = append(lines,
+				nuPrepend("PATH", bin, "''"),
+				nuPrepend("MANPATH", man, "''"),
+				nuPrepend("XDG_DATA_DIRS", share, nuQuote(xdgDataDirs)),
+			)
+		case "pwsh":
+			lines = append(lines,
+				pwshPrepend("PATH", bin),
+				pwshPrepend("MANPATH", man),
+				pwshPrepend("XDG_DATA_DIRS", share),
+			)
+		default:
+			return "", fmt.Errorf("unsupported shell: '%s', expected one of: %s",
+				shell, strings.Join(envShells, ", "))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// posixPrepend prepends dir to the variable unless it is already there, rest
+// is the expansion of the current value appended after dir.
+func posixPrepend(name string, dir string, rest string) string {
+	return fmt.Sprintf(`case ":${%[1]s-}:" in *:%[2]s:*) ;; *) export %[1]s=%[2]s%[3]s ;; esac`,
+		name, posixQuote(dir), rest)
+}
+
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// fishPrepend prepends dir to the variable with the scope unless it is already
+// there.
+func fishPrepend(scope string, name string, dir string) string {
+	return fmt.Sprintf("contains -- %[2]s $%[1]s; or set %[3]s --prepend %[1]s %[2]s",
+		name, fishQuote(dir), scope)
+}
+
+func nuQuote(s string) string {
+	if strings.Contains(s, "'") {
+		return "r#'" + s + "'#"
+	}
+	return "'" + s + "'"
+}
+
+// nuPrepend prepends dir to the variable, which may be a string or a list,
+// keeping only the first of repeated entries.
+func nuPrepend(name string, dir string, empty string) string {
+	return fmt.Sprintf("$env.%[1]s = ($env.%[1]s? | default %[3]s | split row (char esep) "+
+		"| prepend %[2]s | uniq | str join (char esep))", name, nuQuote(dir), empty)
+}
+
+func pwshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func pwshPrepend(name string, dir string) string {
+	return fmt.Sprintf("if (($env:%[1]s -split [IO.Path]::PathSeparator) -notcontains %[2]s) "+
+		"{ $env:%[1]s = %[2]s + [IO.Path]::PathSeparator + $env:%[1]s }", name, pwshQuote(dir))
+}
+
+// notOnPath returns a warning when the bin directory of prefix is not on PATH,
+// so the commands installed into it can't be run by name.
+func notOnPath(prefix string) string {
+	bin := filepath.Join(prefix, "bin")
+	if path.Root != "" || slices.Contains(filepath.SplitList(os.Getenv("PATH")), bin) {
+		return ""
+	}
+	return fmt.Sprintf("'%s' is not on PATH, add it to the shell with: eval \"$(tuck env)\"",
+		path.Contract(bin))
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.Flags().StringVar(&envParams.Shell, "shell", "",
+		"shell to print the configuration for, one of: "+strings.Join(envShells, ", "))
+	envCmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions(
+		envShells, cobra.ShellCompDirectiveNoFileComp))
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"tuck/internal/config"
+	"tuck/internal/log"
+)
+
+// The hooks run around installing and removing packages, see
+// config.HooksConfig.
+const (
+	hookPreInstall  = "pre_install"
+	hookPostInstall = "post_install"
+	hookPreRemove   = "pre_remove"
+	hookPostRemove  = "post_remove"
+)
+
+// hookTarget is the package an operation is performed on, described to hooks
+// by their environment.
+type hookTarget struct {
+	name    string
+	prefix  string
+	version string
+	files   []string
+}
+
+// hookCommand returns the command of the hook, or an empty command when the
+// hook is not configured.
+func hookCommand(hooks config.HooksConfig, hook string) string {
+	switch hook {
+	case hookPreInstall:
+		return hooks.PreInstall
+	case hookPostInstall:
+		return hooks.PostInstall
+	case hookPreRemove:
+		return hooks.PreRemove
+	case hookPostRemove:
+		return hooks.PostRemove
+	default:
+		return ""
+	}
+}
+
+// runHooks runs the global hook then the hook of the package, stopping at the
+// first which fails. Hooks are run with their output on stderr so as not to
+// mix with structured output.
+func runHooks(cfg config.Config, hook string, target hookTarget) error {
+	hooks := []config.HooksConfig{cfg.Hooks}
+	if pkgConfig, found := cfg.Packages[target.name]; found {
+		hooks = append(hooks, pkgConfig.Hooks)
+	}
+	for _, hooksConfig := range hooks {
+		command := hookCommand(hooksConfig, hook)
+		if command == "" {
+			continue
+		}
+		timeout := config.DefaultTimeout
+		if hooksConfig.Timeout > 0 {
+			timeout = hooksConfig.Timeout
+		} else if cfg.Hooks.Timeout > 0 {
+			timeout = cfg.Hooks.Timeout
+		}
+		if err := runHook(hook, command, timeout, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runHook(hook string, command string, timeout time.Duration, target hookTarget) error {
+	log.Infof("running %s hook: %s\n", hook, command)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		// unlike TUCK_PREFIX these don't configure a tuck run by the hook
+		"TUCK_HOOK="+hook,
+		"TUCK_HOOK_PACKAGE="+target.name,
+		"TUCK_HOOK_PREFIX="+target.prefix,
+		"TUCK_HOOK_VERSION="+target.version,
+		"TUCK_HOOK_FILES="+strings.Join(target.files, "\n"),
+	)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s hook timed out after %s", hook, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %w", hook, err)
+	}
+	return nil
+}
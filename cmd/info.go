@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"tuck/internal/config"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/provider"
+	"tuck/internal/state"
+	"tuck/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+var infoParams struct {
+	Package string
+	Json    bool
+}
+
+// packageInfo is the details of an installed package shown by tuck info.
+type packageInfo struct {
+	Name      string              `json:"name" yaml:"name"`
+	Source    string              `json:"source,omitempty" yaml:"source,omitempty"`
+	Provider  string              `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Prefix    string              `json:"prefix" yaml:"prefix"`
+	Release   string              `json:"release" yaml:"release"`
+	Version   string              `json:"version,omitempty" yaml:"version,omitempty"`
+	Versions  []string            `json:"versions,omitempty" yaml:"versions,omitempty"`
+	Asset     string              `json:"asset,omitempty" yaml:"asset,omitempty"`
+	Digest    string              `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Local     bool                `json:"local" yaml:"local"`
+	Installed time.Time           `json:"installed,omitzero" yaml:"installed,omitempty"`
+	DiskUsage int64               `json:"diskUsage" yaml:"diskUsage"`
+	Files     map[string][]string `json:"files" yaml:"files"`
+	Latest    string              `json:"latest,omitempty" yaml:"latest,omitempty"`
+	Outdated  bool                `json:"outdated" yaml:"outdated"`
+}
+
+// fileGroups are the groups files are shown in, in order, with their labels.
+var fileGroups = [][2]string{
+	{"binaries", "binaries"},
+	{"manpages", "man pages"},
+	{"completions", "completions"},
+	{"other", "other files"},
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info [flags] package",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Short: "Show details of an installed package",
+	Long: `Show details of an installed package: where it was installed from and
+to, the requested and installed release, its files and whether a newer release
+is available.`,
+	ValidArgsFunction: installedValidArgsFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		infoParams.Package = args[0]
+		log.Debugf("info: %+v\n", infoParams)
+
+		unlock, err := path.AcquireSharedLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		_, pkg, err := lookupPackage(infoParams.Package)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if pkg == nil {
+			log.Fatalln("package not installed:", infoParams.Package)
+		}
+		info := newPackageInfo(infoParams.Package, *pkg)
+
+		// a direct URL doesn't tell of newer versions
+		if !pkg.Local && !provider.IsArchiveURL(infoParams.Package) {
+			cfg, err := config.Load()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			latest, err := latestRelease(infoParams.Package, cfg)
+			if err != nil {
+				log.Warnln("unable to check for a newer release:", err)
+			} else {
+				info.Latest = latest
+				info.Outdated = isNewer(latest, pkg.Version)
+			}
+		}
+
+		if infoParams.Json {
+			params.Output = OutputJson
+		}
+		if structured() {
+			if err := printStructured(info); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+		printPackageInfo(info)
+	},
+}
+
+func newPackageInfo(name string, pkg state.Package) packageInfo {
+	info := packageInfo{
+		Name:      name,
+		Source:    pkg.Source,
+		Provider:  pkg.Provider,
+		Prefix:    pkg.Prefix,
+		Release:   pkg.Release,
+		Version:   pkg.Version,
+		Versions:  pkg.Versions,
+		Asset:     pkg.Asset,
+		Digest:    pkg.Digest,
+		Local:     pkg.Local,
+		Installed: pkg.Installed,
+		DiskUsage: diskUsage(name, pkg),
+		Files:     map[string][]string{},
+	}
+	if pkg.Archive != "" {
+		info.Source = pkg.Archive
+		info.Digest = "sha256:" + pkg.Sha256
+	}
+	for _, file := range pkg.Files {
+		group := fileGroup(pkg.Prefix, file)
+		info.Files[group] = append(info.Files[group], file)
+	}
+	return info
+}
+
+func printPackageInfo(info packageInfo) {
+	field := func(name string, value string) {
+		if value != "" {
+			fmt.Printf("%-12s %s\n", name+":", value)
+		}
+	}
+	field("name", path.Contract(info.Name))
+	field("source", path.Contract(info.Source))
+	field("provider", info.Provider)
+	field("prefix", path.Contract(info.Prefix))
+	field("release", info.Release)
+	field("version", info.Version)
+	if len(info.Versions) > 1 {
+		field("kept", strings.Join(info.Versions, ", "))
+	}
+	field("asset", info.Asset)
+	field("digest", info.Digest)
+	if !info.Installed.IsZero() {
+		field("installed", info.Installed.Format(time.DateTime))
+	}
+	field("disk usage", formatSize(info.DiskUsage))
+	if info.Latest != "" {
+		latest := "up to date"
+		if info.Outdated {
+			latest = info.Latest + " is available"
+		}
+		field("latest", latest)
+	}
+	for _, group := range fileGroups {
+		files := info.Files[group[0]]
+		if len(files) == 0 {
+			continue
+		}
+		fmt.Printf("%s:\n", group[1])
+		for _, file := range files {
+			fmt.Printf("  %s\n", path.Contract(file))
+		}
+	}
+}
+
+// fileGroup returns the key of the group in fileGroups an installed file
+// belongs to.
+func fileGroup(prefix string, file string) string {
+	rel, err := filepath.Rel(prefix, file)
+	if err != nil {
+		rel = file
+	}
+	rel = filepath.ToSlash(rel)
+	switch {
+	case strings.HasPrefix(rel, "bin/") || strings.HasPrefix(rel, "sbin/"):
+		return "binaries"
+	case strings.HasPrefix(rel, "share/man/") || strings.HasPrefix(rel, "man/"):
+		return "manpages"
+	case strings.Contains(rel, "completion") ||
+		strings.HasPrefix(rel, "share/zsh/") ||
+		strings.HasPrefix(rel, "share/fish/"):
+		return "completions"
+	default:
+		return "other"
+	}
+}
+
+// diskUsage returns the size of every version of the package kept in the
+// store plus the files in the prefix which are not links into the store.
+func diskUsage(name string, pkg state.Package) int64 {
+	size := int64(0)
+	for _, version := range pkg.Versions {
+		filepath.WalkDir(pkg.StorePath(name, version),
+			func(_ string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+					size += info.Size()
+				}
+				return nil
+			})
+	}
+	for _, file := range pkg.Files {
+		if info, err := os.Lstat(file); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// latestRelease returns the version of the latest release of an installed
+// remote package.
+func latestRelease(name string, cfg config.Config) (string, error) {
+	source, repo, err := provider.Lookup(name, cfg.Packages)
+	if err != nil {
+		return "", err
+	}
+	release, err := source.GetRelease(repo, "latest")
+	if err != nil {
+		return "", err
+	}
+	return release.Tag, nil
+}
+
+// isNewer reports whether latest is a newer version than current, versions
+// which can't be compared are newer when they differ.
+func isNewer(latest string, current string) bool {
+	a, errA := version.Parse(latest)
+	b, errB := version.Parse(current)
+	if errA != nil || errB != nil {
+		return latest != current
+	}
+	return version.Compare(a, b) > 0
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+	infoCmd.Flags().BoolVar(&infoParams.Json, "json", false,
+		"print the details as JSON, the same as --output json")
+}
@@ -1,35 +1,94 @@
 package cmd
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 	"tuck/internal/archive"
+	"tuck/internal/cache"
 	"tuck/internal/config"
-	"tuck/internal/github"
+	"tuck/internal/download"
 	"tuck/internal/log"
 	"tuck/internal/path"
+	"tuck/internal/provider"
 	"tuck/internal/state"
 
 	"github.com/spf13/cobra"
 )
 
 var installParams struct {
-	Package string
-	Prefix  string
-	Release string
-	Local   bool
-	DryRun  bool
+	Packages []string
+	Prefix   string
+	Release  string
+	Local    bool
+	Copy     bool
+	Offline  bool
+	Jobs     int
+	DryRun   bool
 }
 
 var installCmd = &cobra.Command{
-	Use:   "install [flags] package",
-	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Short: "Install a local or remote package",
-	Long: `Install a package with a local path or from a GitHub release
-with a project slug or URL.`,
+	Use:   "install [flags] package...",
+	Args:  cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	Short: "Install local or remote packages",
+	Long: `Install packages with a local path or from a release with a project
+slug or URL. The release of each package is either given with --release or
+appended to the package, e.g. owner/repo@v1.2.3. The URL of an archive
+names its release, the package is the URL with the version replaced by
+{{.Version}} so that the URL of another version upgrades it.
+
+Packages are installed into the prefix of the profile selected with
+--profile, see 'profiles' in tuck.yaml, or the prefix given with --prefix,
+otherwise TUCK_PREFIX or 'prefix' in tuck.yaml, see tuck --help. The same
+package may be installed into several prefixes, versions are shared between
+them in the store.
+
+When installing multiple packages their releases are resolved and downloaded
+concurrently, up to --jobs at a time, then installed one at a time. A summary
+is reported and the exit code is non-zero if any package failed to install.
+
+The pre_install and post_install hooks in tuck.yaml are run before and after
+installing each package, a failing pre_install hook skips the package.
+
+Local packages are either a directory or an archive. The files of a directory
+are symlinked into the prefix, or copied with --copy, leaving the directory
+intact. Archives are extracted before being installed and the path and
+checksum of the archive are recorded:
+
+  tuck install --local ./tool
+  tuck install --local --copy /mnt/share/tool
+  tuck install --local ./tool-1.0-linux-amd64.tar.gz
+
+Releases are fetched from GitHub by default, other providers are selected by
+the host of a URL or with a prefix:
+
+  tuck install owner/repo
+  tuck install gitlab:group/project
+  tuck install codeberg:owner/repo
+  tuck install https://gitlab.example.com/group/project
+  tuck install gitea:https://git.example.com/owner/repo
+
+Archives can also be installed directly from a URL, or from a URL template for
+a package configured in the 'packages' section of tuck.yaml:
+
+  tuck install https://example.com/tool-1.2.3-linux-amd64.tar.gz
+  tuck install tool --release 1.2.3
+
+Artifacts in an OCI registry are installed from the layers of the manifest
+matching the host platform:
+
+  tuck install oci://ghcr.io/org/tool:1.2
+
+Downloaded assets are kept in the cache and reused when installing the same
+asset again, with --offline packages are only installed from the cache.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		installParams.Package = args[0]
+		installParams.Packages = args
 		log.Debugf("install: %+v\n", installParams)
 
 		unlock, err := path.AcquireLock()
@@ -38,106 +97,589 @@ with a project slug or URL.`,
 		}
 		defer unlock()
 
-		installParams.Prefix = path.Abs(path.Expand(installParams.Prefix))
-		files := []string{}
+		if installParams.Copy && !installParams.Local {
+			log.Fatalln("--copy is only supported with --local")
+		}
 
 		cfg, err := config.Load()
 		if err != nil {
 			log.Fatalln(err)
 		}
+		profile, err := selectedProfile(cfg)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		installParams.Prefix = profile.Prefix
+		cfg.Filters = *profile.Filters
 		log.Debugln(cfg)
 
-		if installParams.Local {
-			if !path.Exists(installParams.Package) {
-				log.Fatalln("local package does not exist:", installParams.Package)
-			}
-			installParams.Package = path.Abs(installParams.Package)
-
-			// check if a similar package has already been installed?
-			pkg, _ := state.Get(installParams.Package)
-			if pkg != nil {
-				log.Fatalf("package already installed: '%s'\n", installParams.Package)
-			}
+		jobs := []*installJob{}
+		for _, arg := range installParams.Packages {
+			jobs = append(jobs, newInstallJob(arg))
+		}
 
-			// TODO: link instead of move for local packages
-			files = path.Stow(installParams.Package, installParams.Prefix, installParams.DryRun)
-		} else {
-			// TODO: check if a similar package has already been installed?
+		if !installParams.Local {
+			resolveJobs(jobs, cfg)
+		}
+		// warnings of concurrent jobs can't be told apart
+		if warnings := log.Warnings(); len(jobs) == 1 {
+			jobs[0].warnings = warnings
+		}
 
-			release, err := github.GetRelease(installParams.Package, installParams.Release)
-			if err != nil {
-				log.Fatalln(err)
+		// installing modifies the prefix and state so is done one at a time
+		failed := 0
+		for _, job := range jobs {
+			if job.err == nil {
+				if installParams.Local {
+					job.err = job.installLocal(cfg)
+				} else {
+					job.err = job.installRemote(cfg)
+				}
 			}
-			asset, err := github.SelectAsset(release, cfg.Filters)
-			if err != nil {
-				log.Fatalln(err)
+			if job.err == nil && !installParams.DryRun {
+				err := runHooks(cfg, hookPostInstall, job.hookTarget())
+				if err != nil {
+					log.Warnln(err)
+				}
 			}
-
-			archivePath := filepath.Join(path.CacheDir, asset.Name)
-			// TODO: validate checksum
-			err = path.DownloadFile(asset.BrowserDownloadUrl, archivePath)
-			if err != nil {
-				log.Fatalln(err)
+			job.warnings = append(job.warnings, log.Warnings()...)
+			if job.err != nil {
+				log.Errorf("failed to install '%s': %v\n", job.pkg, job.err)
+				failed++
 			}
+		}
 
-			err = archive.Extract(archivePath, path.CacheDir)
-			if err != nil {
-				log.Fatalln(err)
+		if failed < len(jobs) && !installParams.DryRun {
+			if warning := notOnPath(installParams.Prefix); warning != "" {
+				log.Warnln(warning)
+				for _, job := range jobs {
+					if job.err == nil {
+						job.warnings = append(job.warnings, warning)
+					}
+				}
 			}
+		}
 
-			if err := os.Remove(archivePath); err != nil {
-				log.Fatalln(err)
+		if structured() {
+			results := []installResult{}
+			for _, job := range jobs {
+				results = append(results, job.result())
 			}
-
-			// TODO: detect if ~/.cache/tuck contains a 1 directory or multiple
-			// entries
-			entries, err := os.ReadDir(path.CacheDir)
-			if err != nil {
+			if err := printStructured(results); err != nil {
 				log.Fatalln(err)
 			}
-			dir := ""
-			if len(entries) == 1 && entries[0].IsDir() {
-				dir = filepath.Join(path.CacheDir, entries[0].Name())
-			} else {
-				// assume the archive didn't contain a root directory, this
-				// relies on the cache directory being empty
-				dir = path.CacheDir
+		} else if len(jobs) > 1 {
+			fmt.Printf("tuck installed %d of %d packages\n", len(jobs)-failed, len(jobs))
+			for _, job := range jobs {
+				if job.err != nil {
+					fmt.Printf("  failed: %s: %v\n", job.pkg, job.err)
+				} else {
+					fmt.Printf("  installed: %s %s\n", job.pkg, job.version)
+				}
 			}
+		}
+		if failed > 0 {
+			unlock()
+			os.Exit(1)
+		}
+	},
+}
+
+// installJob is the installation of a single package, remote packages are
+// first resolved and downloaded, which may be done concurrently with other
+// jobs, then installed.
+type installJob struct {
+	pkg     string
+	release string
+	err     error
 
-			files = path.Stow(dir, installParams.Prefix, installParams.DryRun)
+	source   provider.Provider
+	asset    provider.Asset
+	version  string
+	provider string
+	cached   *cache.Entry
+	digest   string // of the asset when the version is kept in the store
+
+	files    []string
+	removed  []string
+	warnings []string
+}
 
-			// remove the package directory, cache dir may no longer exist
-			os.RemoveAll(dir)
+// installResult is the outcome of installing a package in the structured
+// output of tuck install.
+type installResult struct {
+	Package  string   `json:"package" yaml:"package"`
+	Version  string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Prefix   string   `json:"prefix" yaml:"prefix"`
+	Success  bool     `json:"success" yaml:"success"`
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
+	Added    []string `json:"added" yaml:"added"`
+	Removed  []string `json:"removed" yaml:"removed"`
+	Warnings []string `json:"warnings" yaml:"warnings"`
+}
+
+func (job *installJob) result() installResult {
+	result := installResult{
+		Package:  job.pkg,
+		Version:  job.version,
+		Prefix:   installParams.Prefix,
+		Success:  job.err == nil,
+		Added:    job.files,
+		Removed:  job.removed,
+		Warnings: job.warnings,
+	}
+	if job.err != nil {
+		result.Error = job.err.Error()
+	}
+	// empty lists rather than null for consumers of the output
+	for _, list := range []*[]string{&result.Added, &result.Removed, &result.Warnings} {
+		if *list == nil {
+			*list = []string{}
 		}
+	}
+	return result
+}
 
-		for _, file := range files {
-			log.Infoln("installed:", file)
+// newInstallJob creates a job for the package argument, which may have a
+// release appended as "package@release" to override --release. The release of
+// an archive URL is the version in the URL.
+func newInstallJob(arg string) *installJob {
+	job := &installJob{pkg: arg, release: installParams.Release}
+	if !installParams.Local && provider.IsArchiveURL(arg) {
+		// the URL of another version upgrades the package
+		if name, version := provider.URLPackage(arg); version != "" {
+			job.pkg = name
+			job.release = version
 		}
+	}
+	// URLs may contain '@' and OCI references use it for digests
+	if !installParams.Local && !strings.Contains(arg, "://") {
+		if at := strings.LastIndex(arg, "@"); at > 0 {
+			job.pkg = arg[:at]
+			job.release = arg[at+1:]
+		}
+	}
+	return job
+}
+
+// resolveJobs resolves and downloads the assets of remote packages using up
+// to the configured number of concurrent jobs.
+func resolveJobs(jobs []*installJob, cfg config.Config) {
+	workers := cfg.Jobs
+	if installParams.Jobs > 0 {
+		workers = installParams.Jobs
+	}
+	if len(jobs) > 1 {
+		// progress bars of concurrent downloads would overwrite each other
+		download.Quiet = true
+	}
+
+	queue := make(chan *installJob)
+	wg := sync.WaitGroup{}
+	for range min(max(workers, 1), len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				job.err = job.resolve(cfg)
+			}
+		}()
+	}
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// resolve finds the release and asset of the package and fetches the asset
+// into the cache, unless the version is already kept in the store.
+func (job *installJob) resolve(cfg config.Config) error {
+	if installParams.Offline {
+		// resolve the release from the cache instead of the provider
+		entry, err := cache.FindRelease(job.pkg, job.release)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return fmt.Errorf("offline and no cached asset for release %s", job.release)
+		}
+		job.provider = entry.Provider
+		job.version = entry.Version
+		job.asset = provider.Asset{Name: entry.Name, Url: entry.Url, Digest: entry.Digest}
+	} else {
+		source, repo, err := provider.Lookup(job.pkg, cfg.Packages)
+		if err != nil {
+			return err
+		}
+		log.Debugf("using %s provider for '%s'\n", source.Name(), repo)
+		job.source = source
+		job.provider = source.Name()
+
+		release, err := source.GetRelease(repo, job.release)
+		if err != nil {
+			return err
+		}
+		job.version = release.Tag
+		job.asset, err = provider.Select(source, release, cfg.Filters)
+		if err != nil {
+			return err
+		}
+	}
+
+	installed, err := state.Get(installParams.Prefix, job.pkg)
+	if err != nil {
+		return err
+	}
+	if installed != nil {
+		if err := checkPin(installed.Pin, job.version); err != nil {
+			return err
+		}
+	}
+	kept, digest, err := storeKept(job.pkg, job.version, job.asset)
+	if err != nil || kept {
+		job.digest = digest
+		return err
+	}
+
+	cached, err := fetchAsset(job.source, job.asset, cache.Entry{
+		Name:     job.asset.Name,
+		Url:      job.asset.Url,
+		Digest:   job.asset.Digest,
+		Package:  job.pkg,
+		Provider: job.provider,
+		Release:  job.release,
+		Version:  job.version,
+	})
+	if err != nil {
+		return err
+	}
+	job.cached = &cached
+	return nil
+}
+
+// hookTarget describes the package being installed to hooks.
+func (job *installJob) hookTarget() hookTarget {
+	return hookTarget{
+		name:    job.pkg,
+		prefix:  installParams.Prefix,
+		version: job.version,
+		files:   job.files,
+	}
+}
+
+// preInstall runs the pre_install hooks unless this is a dry run.
+func (job *installJob) preInstall(cfg config.Config) error {
+	if installParams.DryRun {
+		return nil
+	}
+	return runHooks(cfg, hookPreInstall, job.hookTarget())
+}
+
+// installLocal installs a local directory or archive into the prefix.
+func (job *installJob) installLocal(cfg config.Config) error {
+	if !path.Exists(job.pkg) {
+		return fmt.Errorf("local package does not exist")
+	}
+	job.pkg = path.Abs(job.pkg)
+
+	// check if a similar package has already been installed?
+	installed, err := state.Get(installParams.Prefix, job.pkg)
+	if err != nil {
+		return err
+	}
+	if installed != nil {
+		return fmt.Errorf("package already installed")
+	}
+	if err := job.preInstall(cfg); err != nil {
+		return err
+	}
+
+	pkg := state.Package{
+		Prefix:  installParams.Prefix,
+		Release: job.release,
+		Local:   true,
+		Stow:    string(path.StowMove),
+	}
+	files := []string{}
+	if archive.IsArchive(job.pkg) && !path.IsDir(job.pkg) {
+		pkg.Archive = job.pkg
+		pkg.Sha256, err = path.HashFile(job.pkg)
+		if err != nil {
+			return err
+		}
+		files, err = extractAndStow(job.pkg, installParams.Prefix,
+			installParams.DryRun)
+		if err != nil {
+			return err
+		}
+	} else {
+		stowMode := path.StowLink
+		// links to the host's files would be broken inside the root
+		if installParams.Copy || path.Root != "" {
+			stowMode = path.StowCopy
+		}
+		pkg.Stow = string(stowMode)
+		files, err = path.Stow(job.pkg, installParams.Prefix, stowMode,
+			installParams.DryRun)
+		if err != nil {
+			return err
+		}
+	}
+	return job.record(pkg, files)
+}
+
+// installRemote installs the resolved version of the package into the store
+// and links it into the prefix. Installing over an existing package adds a
+// new version to the store and switches the links in the prefix to it.
+func (job *installJob) installRemote(cfg config.Config) error {
+	installed, err := state.Get(installParams.Prefix, job.pkg)
+	if err != nil {
+		return err
+	}
+	pkg := state.Package{
+		Prefix:   installParams.Prefix,
+		Release:  job.release,
+		Version:  job.version,
+		Provider: job.provider,
+		Source:   job.asset.Url,
+		Asset:    job.asset.Name,
+		Stow:     string(path.StowMove),
+	}
+	previousFiles := []string{}
+	if installed != nil {
+		if installed.Local {
+			return fmt.Errorf("package already installed as a local package")
+		}
+		if err := checkPin(installed.Pin, job.version); err != nil {
+			return err
+		}
+		pkg.Versions = installed.Versions
+		pkg.Assets = maps.Clone(installed.Assets)
+		pkg.Previous = snapshot(*installed)
+		if installed.Version == job.version {
+			// reinstalling relinks the version, repairing the prefix
+			log.Infof("reinstalling version %s\n", job.version)
+			pkg.Previous = installed.Previous
+			pkg.Digest = installed.Digest
+		}
+		pkg.Pin = installed.Pin
+		previousFiles = installed.Files
+	}
+	if err := job.preInstall(cfg); err != nil {
+		return err
+	}
+
+	storeDir := path.StorePath(job.pkg, job.version, job.asset.Name)
+	storeFiles := []string{}
+	if job.cached == nil {
+		log.Infof("using version %s kept in the store\n", job.version)
+		pkg.Digest = job.digest
+		storeFiles, err = path.StoreFiles(storeDir)
+	} else {
+		pkg.Digest = job.cached.Digest
+		storeFiles, err = installToStore(job.cached.Path(), storeDir,
+			installParams.DryRun)
+	}
+	if err != nil {
+		return err
+	}
+
+	files, err := linkVersion(storeDir, storeFiles, installParams.Prefix,
+		previousFiles, installParams.DryRun)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(pkg.Versions, job.version) {
+		pkg.Versions = append(pkg.Versions, job.version)
+	}
+	if pkg.Assets == nil {
+		pkg.Assets = map[string]string{}
+	}
+	if replaced, found := pkg.Assets[job.version]; found && replaced != job.asset.Name &&
+		!installParams.DryRun {
+		// the version was installed from another asset before
+		key := state.Key{Prefix: installParams.Prefix, Name: job.pkg}
+		if err := removeStoreVersion(key, job.version, replaced); err != nil {
+			log.Warnln(err)
+		}
+	}
+	pkg.Assets[job.version] = job.asset.Name
+	if installed != nil {
+		pruneVersions(job.pkg, &pkg, cfg.Retention)
+	}
+	for _, file := range previousFiles {
+		if !slices.Contains(files, file) {
+			job.removed = append(job.removed, file)
+		}
+	}
+	return job.record(pkg, files)
+}
+
+// record reports the installed files and stores the package state.
+func (job *installJob) record(pkg state.Package, files []string) error {
+	for _, file := range files {
+		log.Infoln("installed:", file)
+	}
+	job.files = files
+	if !structured() {
 		fmt.Printf("tuck installed %d files from '%s' into '%s'\n",
-			len(files), path.Contract(installParams.Package),
-			path.Contract(installParams.Prefix))
-
-		if !installParams.DryRun {
-			// store list of files installed by package
-			state.Install(installParams.Package, state.Package{
-				Prefix:  installParams.Prefix,
-				Release: installParams.Release,
-				Local:   installParams.Local,
-				Files:   files,
-			})
+			len(files), path.Contract(job.pkg), path.Contract(installParams.Prefix))
+	}
+
+	if installParams.DryRun {
+		return nil
+	}
+	// store list of files installed by package
+	pkg.Files = files
+	pkg.Checksums = checksumFiles(files)
+	pkg.Modes = modeFiles(files)
+	pkg.Installed = time.Now()
+	return state.Install(job.pkg, pkg)
+}
+
+// extractAndStow extracts the archive into a staging directory then stows its
+// content into prefix, the staging directory is always removed.
+func extractAndStow(archivePath string, prefix string, dryRun bool) ([]string, error) {
+	staging, err := os.MkdirTemp(path.CacheDir, "staging-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := archive.Extract(archivePath, staging); err != nil {
+		return nil, err
+	}
+	if path.System {
+		if err := path.Normalize(staging); err != nil {
+			return nil, err
 		}
-	},
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return nil, err
+	}
+	dir := staging
+	if len(entries) == 1 && entries[0].IsDir() {
+		// the archive contains a single root directory
+		dir = filepath.Join(staging, entries[0].Name())
+	}
+	return path.Stow(dir, prefix, path.StowMove, dryRun)
+}
+
+// fetchAsset returns the cache entry of the asset, downloading it into the
+// cache when it is not already present. Without a source only cached assets
+// are available.
+func fetchAsset(source provider.Provider, asset provider.Asset, entry cache.Entry) (cache.Entry, error) {
+	// a concurrent download of the same URL is found in the cache once done
+	defer cache.LockDownload(asset.Url)()
+	cached, err := cache.Find(asset.Digest, asset.Url, entry.Version)
+	if err != nil {
+		return entry, err
+	}
+	if cached != nil {
+		log.Infoln("using cached asset:", path.Contract(cached.Path()))
+		// the asset may have been downloaded for another package
+		used := *cached
+		used.Package, used.Release = entry.Package, entry.Release
+		used.Provider = cmp.Or(entry.Provider, used.Provider)
+		used.Version = cmp.Or(entry.Version, used.Version)
+		return used, cache.Touch(used)
+	}
+	if source == nil {
+		return entry, fmt.Errorf("asset is not cached: '%s'", asset.Name)
+	}
+
+	// a partial download is resumed by a later attempt from the same URL
+	download := filepath.Join(cache.PartialDir(asset.Url), asset.Name)
+	if err := os.MkdirAll(filepath.Dir(download), os.ModePerm); err != nil {
+		return entry, err
+	}
+	if err := source.Download(asset, download); err != nil {
+		return entry, err
+	}
+	defer os.RemoveAll(filepath.Dir(download))
+	return cache.Add(download, entry)
+}
+
+// installToStore extracts the archive into a new store directory, the
+// directory is populated in a temporary location then renamed into place so
+// it is never left partially installed.
+func installToStore(archivePath string, storeDir string, dryRun bool) ([]string, error) {
+	if dryRun {
+		return extractAndStow(archivePath, storeDir, true)
+	}
+	if err := os.MkdirAll(filepath.Dir(storeDir), os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(storeDir), ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if _, err := extractAndStow(archivePath, tmp, false); err != nil {
+		return nil, err
+	}
+	// the temporary directory is private until it is renamed into place
+	if path.System {
+		if err := os.Chmod(tmp, path.ExecMode); err != nil {
+			return nil, err
+		}
+	}
+	// a directory without a state entry is left over from an earlier failure
+	if err := os.RemoveAll(storeDir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, storeDir); err != nil {
+		return nil, err
+	}
+	return path.StoreFiles(storeDir)
+}
+
+// checksumFiles returns the sha256 checksums of the content of files, used to
+// detect changes to installed files later.
+func checksumFiles(files []string) map[string]string {
+	checksums := map[string]string{}
+	for _, file := range files {
+		checksum, err := path.HashFile(file)
+		if err != nil {
+			log.Warnln(err)
+			continue
+		}
+		checksums[file] = checksum
+	}
+	return checksums
+}
+
+// modeFiles returns the modes of files, used to detect changes to the type or
+// permissions of installed files later.
+func modeFiles(files []string) map[string]os.FileMode {
+	modes := map[string]os.FileMode{}
+	for _, file := range files {
+		mode, err := path.FileMode(file)
+		if err != nil {
+			log.Warnln(err)
+			continue
+		}
+		modes[file] = mode
+	}
+	return modes
 }
 
 func init() {
 	rootCmd.AddCommand(installCmd)
 	installCmd.Aliases = append(installCmd.Aliases, "in")
-	installCmd.Flags().StringVarP(&installParams.Prefix, "prefix", "p",
-		"~/.local", "install prefix path")
 	installCmd.Flags().StringVarP(&installParams.Release, "release", "r",
 		"latest", "github release to install")
 	installCmd.Flags().BoolVarP(&installParams.Local, "local", "l", false,
 		"treat package as local path")
+	installCmd.Flags().BoolVarP(&installParams.Copy, "copy", "c", false,
+		"copy local package files instead of symlinking them, always with --root")
+	installCmd.Flags().BoolVar(&installParams.Offline, "offline", false,
+		"install only from previously downloaded assets in the cache")
+	installCmd.Flags().IntVarP(&installParams.Jobs, "jobs", "j", 0,
+		"number of packages to download concurrently (default from config)")
 	installCmd.Flags().BoolVarP(&installParams.DryRun, "dry-run", "d", false,
 		"don't actually install anything")
 }
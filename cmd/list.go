@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"tuck/internal/log"
+	"tuck/internal/path"
 	"tuck/internal/state"
 
 	"github.com/spf13/cobra"
@@ -12,26 +14,100 @@ var listParams struct {
 	Quiet bool
 }
 
+// listEntry is an installed package in the structured output of tuck list.
+type listEntry struct {
+	Name     string     `json:"name" yaml:"name"`
+	Version  string     `json:"version,omitempty" yaml:"version,omitempty"`
+	Release  string     `json:"release" yaml:"release"`
+	Provider string     `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Source   string     `json:"source,omitempty" yaml:"source,omitempty"`
+	Prefix   string     `json:"prefix" yaml:"prefix"`
+	Local    bool       `json:"local" yaml:"local"`
+	Pin      *state.Pin `json:"pin,omitempty" yaml:"pin,omitempty"`
+	Files    []string   `json:"files" yaml:"files"`
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Args:  cobra.MatchAll(cobra.OnlyValidArgs),
 	Short: "List installed packages",
-	Long:  `List installed packages.`,
+	Long: `List installed packages, only those in the prefix of the profile
+selected with --profile or the prefix given with --prefix when either is
+given.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Debugf("list: %+v\n", listParams)
 
-		pkgs, err := state.GetAll()
+		unlock, err := path.AcquireSharedLock()
 		if err != nil {
 			log.Fatalln(err)
 		}
-		if !listParams.Quiet {
-			fmt.Println(len(*pkgs), "packages are installed")
+		defer unlock()
+
+		pkgs, err := selectedPackages()
+		if err != nil {
+			log.Fatalln(err)
 		}
-		for name, pkg := range *pkgs {
-			fmt.Printf("%s\n", name)
-			if log.Level <= log.LevelInfo {
-				for _, file := range pkg.Files {
-					fmt.Printf("  %s\n", file)
+		keys := sortedKeys(pkgs)
+
+		switch {
+		case structured():
+			result := []listEntry{}
+			for _, key := range keys {
+				pkg := pkgs[key]
+				result = append(result, listEntry{
+					Name:     key.Name,
+					Version:  pkg.Version,
+					Release:  pkg.Release,
+					Provider: pkg.Provider,
+					Source:   pkg.Source,
+					Prefix:   pkg.Prefix,
+					Local:    pkg.Local,
+					Pin:      pkg.Pin,
+					Files:    pkg.Files,
+				})
+			}
+			if err := printStructured(result); err != nil {
+				log.Fatalln(err)
+			}
+		case params.Output == OutputTable:
+			rows := [][]string{}
+			for _, key := range keys {
+				pkg := pkgs[key]
+				version := pkg.Version
+				if pkg.Local {
+					version = "local"
+				}
+				pinned := ""
+				if pkg.Pin != nil {
+					pinned = pkg.Pin.Version
+				}
+				rows = append(rows, []string{path.Contract(key.Name), version,
+					pkg.Provider, path.Contract(pkg.Prefix), strconv.Itoa(len(pkg.Files)),
+					pinned})
+			}
+			printTable([]string{"NAME", "VERSION", "PROVIDER", "PREFIX", "FILES",
+				"PINNED"}, rows)
+		default:
+			if !listParams.Quiet {
+				fmt.Println(len(pkgs), "packages are installed")
+			}
+			prefixes := map[string]bool{}
+			for key := range pkgs {
+				prefixes[key.Prefix] = true
+			}
+			for _, key := range keys {
+				line := key.Name
+				if len(prefixes) > 1 {
+					line += " in " + path.Contract(key.Prefix)
+				}
+				if pin := pkgs[key].Pin; pin != nil {
+					line += fmt.Sprintf(" (pinned to %s)", describePin(*pin))
+				}
+				fmt.Println(line)
+				if log.Level <= log.LevelInfo {
+					for _, file := range pkgs[key].Files {
+						fmt.Printf("  %s\n", file)
+					}
 				}
 			}
 		}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"tuck/internal/config"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/provider"
+	"tuck/internal/state"
+	"tuck/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+// outdatedEntry is the result of checking a package for a newer release.
+type outdatedEntry struct {
+	Name      string `json:"name" yaml:"name"`
+	Prefix    string `json:"prefix" yaml:"prefix"`
+	Installed string `json:"installed" yaml:"installed"`
+	Latest    string `json:"latest,omitempty" yaml:"latest,omitempty"`
+	Published string `json:"published,omitempty" yaml:"published,omitempty"`
+	Pinned    string `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	Outdated  bool   `json:"outdated" yaml:"outdated"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// outdatedCheck is a package to be checked for a newer release, constraint
+// limits which releases are considered when it was installed with a release
+// other than latest.
+type outdatedCheck struct {
+	name       string
+	pkg        state.Package
+	source     provider.Provider
+	repo       string
+	constraint version.Constraint
+	latest     *provider.Release
+	err        error
+}
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Args:  cobra.NoArgs,
+	Short: "List installed packages with newer releases",
+	Long: `Check every installed remote package against its source and list the
+installed and latest versions. Packages installed with a release which is a
+version constraint are only compared with releases matching it, e.g. a package
+installed with --release 1.2 or --release '^1.2' is only outdated by a newer
+1.2.x or 1.x release. Pinned packages are only outdated by newer versions
+matching their pin.
+
+Packages are checked concurrently, releases of GitHub packages are fetched
+for many packages at a time with the GraphQL API when a token is available
+from GITHUB_TOKEN, GH_TOKEN or gh.
+
+The exit code is 1 when any package is outdated, 2 when a package could not be
+checked and 0 otherwise.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		unlock, err := path.AcquireSharedLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		pkgs, err := selectedPackages()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		checks := []*outdatedCheck{}
+		for _, key := range sortedKeys(pkgs) {
+			pkg := pkgs[key]
+			if pkg.Local {
+				continue
+			}
+			checks = append(checks, newOutdatedCheck(key.Name, pkg, cfg))
+		}
+		batchGitHub(checks)
+		runChecks(checks, cfg.Jobs)
+
+		entries := []outdatedEntry{}
+		outdated, failed := 0, 0
+		for _, check := range checks {
+			entry := check.entry()
+			if entry.Outdated {
+				outdated++
+			}
+			if check.err != nil {
+				log.Errorf("failed to check '%s': %v\n", check.name, check.err)
+				failed++
+			}
+			entries = append(entries, entry)
+		}
+
+		if structured() {
+			if err := printStructured(entries); err != nil {
+				log.Fatalln(err)
+			}
+		} else {
+			rows := [][]string{}
+			for _, entry := range entries {
+				if entry.Outdated || log.Level <= log.LevelInfo {
+					rows = append(rows, []string{path.Contract(entry.Name),
+						path.Contract(entry.Prefix), entry.Installed, entry.Latest,
+						entry.Published, entry.Pinned})
+				}
+			}
+			if len(rows) > 0 {
+				printTable([]string{"NAME", "PREFIX", "INSTALLED", "LATEST", "PUBLISHED",
+					"PINNED"}, rows)
+			}
+			if params.Output == OutputPlain {
+				fmt.Printf("%d of %d packages are outdated\n", outdated, len(entries))
+			}
+		}
+
+		switch {
+		case outdated > 0:
+			unlock()
+			os.Exit(1)
+		case failed > 0:
+			unlock()
+			os.Exit(2)
+		}
+	},
+}
+
+func newOutdatedCheck(name string, pkg state.Package, cfg config.Config) *outdatedCheck {
+	check := &outdatedCheck{name: name, pkg: pkg}
+	check.source, check.repo, check.err = provider.Lookup(name, cfg.Packages)
+	if check.err != nil {
+		return check
+	}
+	if provider.IsArchiveURL(name) {
+		// a direct URL doesn't tell of newer versions
+		check.latest = &provider.Release{Tag: pkg.Version}
+		return check
+	}
+	if pkg.Pin != nil {
+		var err error
+		check.constraint, err = version.ParseConstraint(pkg.Pin.Version)
+		if err != nil {
+			// pinned to a release which is not a version, nothing is newer
+			check.latest = &provider.Release{Tag: pkg.Version}
+		}
+		return check
+	}
+	// a release naming the exact version installed is not a constraint
+	if pkg.Release != "latest" && pkg.Release != pkg.Version {
+		check.constraint, check.err = version.ParseConstraint(pkg.Release)
+	}
+	return check
+}
+
+// batchGitHub fetches the releases of packages from GitHub with as few
+// requests as possible, packages which are not resolved are checked on their
+// own later.
+func batchGitHub(checks []*outdatedCheck) {
+	batches := map[string][]*outdatedCheck{}
+	sources := map[string]*provider.GitHub{}
+	for _, check := range checks {
+		if github, ok := check.source.(*provider.GitHub); ok && check.err == nil {
+			batches[github.ApiUrl] = append(batches[github.ApiUrl], check)
+			sources[github.ApiUrl] = github
+		}
+	}
+	for apiUrl, batch := range batches {
+		repos := []string{}
+		for _, check := range batch {
+			repos = append(repos, check.repo)
+		}
+		releases, err := sources[apiUrl].BatchReleases(repos)
+		if err != nil {
+			log.Debugln("falling back to the REST API:", err)
+			continue
+		}
+		for _, check := range batch {
+			if list, found := releases[check.repo]; found {
+				check.latest, check.err = newestRelease(list, check.constraint)
+			}
+		}
+	}
+}
+
+// runChecks checks the packages which have not been resolved yet, up to jobs
+// at a time.
+func runChecks(checks []*outdatedCheck, jobs int) {
+	queue := make(chan *outdatedCheck)
+	wg := sync.WaitGroup{}
+	for range max(jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range queue {
+				check.latest, check.err = check.run()
+			}
+		}()
+	}
+	for _, check := range checks {
+		if check.latest == nil && check.err == nil {
+			queue <- check
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// run finds the newest release matching the constraint, listing the
+// releases when there is a constraint and the provider supports it.
+func (check *outdatedCheck) run() (*provider.Release, error) {
+	if lister, ok := check.source.(provider.Lister); ok && len(check.constraint) > 0 {
+		releases, err := lister.ListReleases(check.repo)
+		if err != nil {
+			return nil, err
+		}
+		return newestRelease(releases, check.constraint)
+	}
+	release, err := check.source.GetRelease(check.repo, "latest")
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// newestRelease returns the greatest release matching constraint, without a
+// constraint the newest release which isn't a pre-release is used when the
+// tags are not versions.
+func newestRelease(releases []provider.Release, constraint version.Constraint) (*provider.Release, error) {
+	tags := []string{}
+	for _, release := range releases {
+		tags = append(tags, release.Tag)
+	}
+	tag, err := version.Latest(tags, constraint)
+	if err != nil && len(constraint) > 0 {
+		return nil, err
+	}
+	for _, release := range releases {
+		if (err == nil && release.Tag == tag) || (err != nil && !release.Prerelease) {
+			return &release, nil
+		}
+	}
+	return nil, fmt.Errorf("no releases found")
+}
+
+func (check *outdatedCheck) entry() outdatedEntry {
+	entry := outdatedEntry{Name: check.name, Prefix: check.pkg.Prefix,
+		Installed: check.pkg.Version}
+	if check.pkg.Pin != nil {
+		entry.Pinned = check.pkg.Pin.Version
+	}
+	if check.err != nil {
+		entry.Error = check.err.Error()
+		return entry
+	}
+	entry.Latest = check.latest.Tag
+	if len(check.latest.PublishedAt) >= len("2006-01-02") {
+		entry.Published = check.latest.PublishedAt[:len("2006-01-02")]
+	}
+	if len(check.constraint) > 0 {
+		latest, err := version.Parse(entry.Latest)
+		if err != nil || !check.constraint.Check(latest) {
+			return entry
+		}
+	}
+	entry.Outdated = isNewer(entry.Latest, entry.Installed)
+	return entry
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+}
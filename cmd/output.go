@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// Output formats selected with --output, plain is the default human readable
+// output of each command.
+const (
+	OutputPlain = "plain"
+	OutputTable = "table"
+	OutputJson  = "json"
+	OutputYaml  = "yaml"
+)
+
+var outputFormats = []string{OutputPlain, OutputTable, OutputJson, OutputYaml}
+
+// structured reports whether results are printed as JSON or YAML, in which
+// case the human readable messages of commands are not printed.
+func structured() bool {
+	return params.Output == OutputJson || params.Output == OutputYaml
+}
+
+// printStructured prints the result of a command as JSON or YAML.
+func printStructured(result any) error {
+	if params.Output == OutputYaml {
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printTable prints rows aligned in columns under a header.
+func printTable(header []string, rows [][]string) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	writer.Flush()
+}
+
+func validateOutput() error {
+	if !slices.Contains(outputFormats, params.Output) {
+		return fmt.Errorf("invalid --output '%s', expected one of: %s",
+			params.Output, strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
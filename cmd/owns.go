@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+var ownsCmd = &cobra.Command{
+	Use:   "owns [flags] path",
+	Args:  cobra.ExactArgs(1),
+	Short: "Show which package installed a file",
+	Long: `Show which package installed a file, with the package's version and
+source. Exits with an error when the file is not managed by tuck.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Debugf("owns: %+v\n", args)
+
+		unlock, err := path.AcquireSharedLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		if !reportOwner(path.Abs(args[0])) {
+			unlock()
+			os.Exit(1)
+		}
+	},
+}
+
+// reportOwner prints the package which installed file and returns whether
+// there is one.
+func reportOwner(file string) bool {
+	key, pkg, err := state.Owner(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if pkg == nil {
+		// the file may have been given through a symlinked directory, such as
+		// a prefix that is itself a symlink
+		if dir, err := filepath.EvalSymlinks(filepath.Dir(file)); err == nil {
+			key, pkg, err = state.Owner(filepath.Join(dir, filepath.Base(file)))
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+	}
+	if pkg == nil {
+		fmt.Printf("%s is not managed by tuck\n", path.Contract(file))
+		return false
+	}
+
+	version := pkg.Version
+	if version == "" {
+		version = pkg.Release
+	}
+	source := pkg.Source
+	if source == "" {
+		source = key.Name
+	}
+	fmt.Printf("%s is owned by %s %s (%s)\n", path.Contract(file),
+		path.Contract(key.Name), version, path.Contract(source))
+	return true
+}
+
+func init() {
+	rootCmd.AddCommand(ownsCmd)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/state"
+	"tuck/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+var pinParams struct {
+	Package string
+	Version string
+	Reason  string
+}
+
+var pinCmd = &cobra.Command{
+	Use:   "pin [flags] package [version]",
+	Args:  cobra.MatchAll(cobra.RangeArgs(1, 2), cobra.OnlyValidArgs),
+	Short: "Hold a package at a version",
+	Long: `Hold a package at its installed version, or at the versions matching a
+version constraint such as 1.2 or '>=1.2, <2', until it is unpinned. Installing
+or switching to other versions of a pinned package fails and tuck outdated
+only reports newer versions matching the pin.
+
+  tuck pin owner/repo --reason "must match the server version"
+  tuck pin owner/repo 1.2`,
+	ValidArgsFunction: installedValidArgsFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		pinParams.Package = args[0]
+		if len(args) > 1 {
+			pinParams.Version = args[1]
+		}
+		log.Debugf("pin: %+v\n", pinParams)
+
+		unlock, err := path.AcquireLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		_, pkg, err := lookupPackage(pinParams.Package)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if pkg == nil {
+			log.Fatalln("package not installed:", pinParams.Package)
+		}
+		if pkg.Local {
+			log.Fatalln("local packages have no versions to pin:", pinParams.Package)
+		}
+
+		pin := state.Pin{Version: pinParams.Version, Reason: pinParams.Reason,
+			Pinned: time.Now()}
+		if pin.Version == "" {
+			pin.Version = "=" + pkg.Version
+		}
+		if _, err := version.ParseConstraint(pin.Version); err != nil &&
+			pin.Version != "="+pkg.Version {
+			log.Fatalln(err)
+		}
+		if err := checkPin(&pin, pkg.Version); err != nil {
+			log.Warnf("the installed version %s does not match the pin\n", pkg.Version)
+		}
+
+		pkg.Pin = &pin
+		if err := state.Install(pinParams.Package, *pkg); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("tuck pinned '%s' to %s\n", pinParams.Package, pin.Version)
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:               "unpin [flags] package",
+	Args:              cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Short:             "Allow a pinned package to change version again",
+	ValidArgsFunction: installedValidArgsFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		log.Debugf("unpin: %s\n", name)
+
+		unlock, err := path.AcquireLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		_, pkg, err := lookupPackage(name)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if pkg == nil {
+			log.Fatalln("package not installed:", name)
+		}
+		if pkg.Pin == nil {
+			fmt.Printf("tuck has not pinned '%s'\n", name)
+			return
+		}
+		pkg.Pin = nil
+		if err := state.Install(name, *pkg); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("tuck unpinned '%s'\n", name)
+	},
+}
+
+// describePin returns the pinned version with the reason for the pin.
+func describePin(pin state.Pin) string {
+	if pin.Reason == "" {
+		return pin.Version
+	}
+	return fmt.Sprintf("%s: %s", pin.Version, pin.Reason)
+}
+
+// checkPin returns an error when the pin does not allow the version, a pin
+// to a release which is not a version only allows that exact release.
+func checkPin(pin *state.Pin, v string) error {
+	if pin == nil || pin.Version == "="+v {
+		return nil
+	}
+	constraint, err := version.ParseConstraint(pin.Version)
+	parsed, parseErr := version.Parse(v)
+	if err != nil || parseErr != nil || !constraint.Check(parsed) {
+		return fmt.Errorf("package is pinned to %s (use tuck unpin to allow %s)",
+			describePin(*pin), v)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+	pinCmd.Flags().StringVar(&pinParams.Reason, "reason", "",
+		"why the package is pinned")
+}
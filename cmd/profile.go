@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"tuck/internal/config"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+// selectedProfile returns the profile selected with --profile, with its prefix
+// replaced by --prefix when given, made absolute and moved inside --root.
+func selectedProfile(cfg config.Config) (config.ProfileConfig, error) {
+	profile, err := cfg.Profile(params.Profile)
+	if err != nil {
+		return profile, err
+	}
+	if params.Prefix != "" {
+		profile.Prefix = params.Prefix
+	}
+	profile.Prefix = prefixPath(profile.Prefix)
+	return profile, nil
+}
+
+// prefixPath returns the absolute path of a configured prefix inside --root.
+func prefixPath(prefix string) string {
+	return path.Rooted(path.Abs(path.Expand(prefix)))
+}
+
+// selectedPrefix returns the prefix selected with --prefix or --profile, or
+// an empty prefix when neither was given so packages are found in any prefix.
+func selectedPrefix() string {
+	if params.Profile == "" && params.Prefix == "" {
+		return ""
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	profile, err := selectedProfile(cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return profile.Prefix
+}
+
+// lookupPackage finds an installed package in the selected prefix, the
+// package is nil when it is not installed.
+func lookupPackage(name string) (state.Key, *state.Package, error) {
+	key, pkg, err := state.Lookup(name, selectedPrefix())
+	if errors.Is(err, state.ErrAmbiguous) {
+		err = fmt.Errorf("%w, select one with --profile or --prefix", err)
+	}
+	return key, pkg, err
+}
+
+// selectedPackages returns the installed packages in the selected prefix.
+func selectedPackages() (state.State, error) {
+	pkgs, err := state.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	prefix := selectedPrefix()
+	selected := state.State{}
+	for key, pkg := range *pkgs {
+		if prefix == "" || key.Prefix == prefix {
+			selected[key] = pkg
+		}
+	}
+	return selected, nil
+}
+
+// sortedKeys returns the keys of the packages ordered by name then prefix.
+func sortedKeys(pkgs state.State) []state.Key {
+	keys := slices.Collect(maps.Keys(pkgs))
+	slices.SortFunc(keys, func(a state.Key, b state.Key) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Prefix, b.Prefix))
+	})
+	return keys
+}
+
+func profileCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return slices.Sorted(maps.Keys(cfg.Profiles)), cobra.ShellCompDirectiveNoFileComp
+}
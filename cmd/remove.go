@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"tuck/internal/config"
 	"tuck/internal/log"
 	"tuck/internal/path"
 	"tuck/internal/state"
@@ -14,13 +16,25 @@ var removeParams struct {
 	Package string
 }
 
+// removeResult is the outcome of removing a package in the structured output
+// of tuck remove.
+type removeResult struct {
+	Package  string   `json:"package" yaml:"package"`
+	Version  string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Prefix   string   `json:"prefix" yaml:"prefix"`
+	Removed  []string `json:"removed" yaml:"removed"`
+	Warnings []string `json:"warnings" yaml:"warnings"`
+}
+
 var removeCmd = &cobra.Command{
 	Use:   "remove [flags] package",
 	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	Short: "Remove an installed package",
 	Long: `Remove a package with a local path or from a GitHub release
-with a project slug or URL.`,
-	ValidArgsFunction: removeValidArgsFunc,
+with a project slug or URL. A package installed into more than one prefix is
+selected with --profile or --prefix. The pre_remove and post_remove hooks in
+tuck.yaml are run before and after, a failing pre_remove hook keeps the package.`,
+	ValidArgsFunction: installedValidArgsFunc,
 	Run: func(cmd *cobra.Command, args []string) {
 		removeParams.Package = args[0]
 		log.Infof("remove: %+v\n", removeParams)
@@ -31,7 +45,7 @@ with a project slug or URL.`,
 		}
 		defer unlock()
 
-		pkg, err := state.Get(removeParams.Package)
+		key, pkg, err := lookupPackage(removeParams.Package)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -39,27 +53,83 @@ with a project slug or URL.`,
 			log.Errorln("package not installed:", removeParams.Package)
 			return
 		}
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		target := hookTarget{name: key.Name, prefix: key.Prefix, version: pkg.Version,
+			files: pkg.Files}
+		if err := runHooks(cfg, hookPreRemove, target); err != nil {
+			log.Fatalln(err)
+		}
 		// remove files
+		removed := []string{}
 		for _, file := range pkg.Files {
-			os.Remove(file)
+			// a file installed over by another package is left to it
+			owner, _, err := state.Owner(file)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if owner.Name != "" && owner != key {
+				log.Warnf("not removing '%s' installed by '%s'\n", path.Contract(file),
+					path.Contract(owner.Name))
+				continue
+			}
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				log.Warnln(err)
+				continue
+			}
+			removed = append(removed, file)
 			log.Infoln("removed:", file)
 		}
-		state.Remove(removeParams.Package)
+		// remove every version kept in the store
+		for _, version := range pkg.Versions {
+			if err := removeStoreVersion(key, version, pkg.Assets[version]); err != nil {
+				log.Warnln(err)
+			}
+		}
+		if err := state.Remove(key.Prefix, key.Name); err != nil {
+			log.Fatalln(err)
+		}
+		if err := runHooks(cfg, hookPostRemove, target); err != nil {
+			log.Warnln(err)
+		}
+		// TODO: remove empty directories
+
+		if structured() {
+			warnings := log.Warnings()
+			if warnings == nil {
+				warnings = []string{}
+			}
+			err := printStructured(removeResult{
+				Package:  removeParams.Package,
+				Version:  pkg.Version,
+				Prefix:   pkg.Prefix,
+				Removed:  removed,
+				Warnings: warnings,
+			})
+			if err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
 		fmt.Printf("tuck removed %d files from '%s' out of '%s'\n",
-			len(pkg.Files), path.Contract(removeParams.Package),
+			len(removed), path.Contract(removeParams.Package),
 			path.Contract(pkg.Prefix))
-		// TODO: remove empty directories
 	},
 }
 
-func removeValidArgsFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+// installedValidArgsFunc completes the names of installed packages.
+func installedValidArgsFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	var completions []string
 	pkgs, err := state.GetAll()
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError
 	}
-	for name := range *pkgs {
-		completions = append(completions, name)
+	for key := range *pkgs {
+		if !slices.Contains(completions, key.Name) {
+			completions = append(completions, key.Name)
+		}
 	}
 	return completions, cobra.ShellCompDirectiveNoFileComp
 }
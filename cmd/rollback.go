@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/provider"
+	"tuck/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+var rollbackParams struct {
+	Package string
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [flags] package",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Short: "Roll back a package to the previously installed version",
+	Long: `Roll back a package to the version which was active before it was
+last upgraded or switched. The previous version is restored from the store
+along with its state entry, rolling back again returns to the version which
+was rolled back from.
+
+The number of versions kept in the store for each package is set by
+'retention' in tuck.yaml.`,
+	ValidArgsFunction: installedValidArgsFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		rollbackParams.Package = args[0]
+		log.Debugf("rollback: %+v\n", rollbackParams)
+
+		unlock, err := path.AcquireLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		_, pkg, err := lookupPackage(rollbackParams.Package)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if pkg == nil {
+			log.Fatalln("package not installed:", rollbackParams.Package)
+		}
+		if pkg.Local {
+			log.Fatalln("local packages can not be rolled back:", rollbackParams.Package)
+		}
+		if pkg.Previous == nil {
+			log.Fatalln("no previous version to roll back to:", rollbackParams.Package)
+		}
+		previous := *pkg.Previous
+		if !slices.Contains(pkg.Versions, previous.Version) ||
+			!path.Exists(pkg.StorePath(rollbackParams.Package, previous.Version)) {
+			log.Fatalf("previous version %s of '%s' is no longer kept in the store\n",
+				previous.Version, rollbackParams.Package)
+		}
+
+		current := pkg.Version
+		if err := activate(rollbackParams.Package, pkg, previous); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("tuck rolled back '%s' from %s to %s\n", rollbackParams.Package,
+			current, previous.Version)
+	},
+}
+
+// snapshot copies a package entry to be kept as the previous entry, only one
+// previous entry is kept.
+func snapshot(pkg state.Package) *state.Package {
+	pkg.Versions = nil
+	pkg.Assets = nil
+	pkg.Previous = nil
+	pkg.Pin = nil
+	return &pkg
+}
+
+// pruneVersions removes the oldest versions of a package from the store until
+// no more than retention versions are kept, the active version is never
+// removed and the previous version is removed last.
+func pruneVersions(name string, pkg *state.Package, retention int) {
+	for len(pkg.Versions) > retention {
+		i := slices.IndexFunc(pkg.Versions, func(version string) bool {
+			return version != pkg.Version &&
+				(pkg.Previous == nil || version != pkg.Previous.Version)
+		})
+		if i == -1 {
+			i = slices.IndexFunc(pkg.Versions, func(version string) bool {
+				return version != pkg.Version
+			})
+		}
+		if i == -1 {
+			break
+		}
+		version := pkg.Versions[i]
+		key := state.Key{Prefix: pkg.Prefix, Name: name}
+		if err := removeStoreVersion(key, version, pkg.Assets[version]); err != nil {
+			log.Warnln(err)
+		}
+		log.Infof("removed version %s of '%s' from the store\n", version, name)
+		pkg.Versions = slices.Delete(pkg.Versions, i, i+1)
+		delete(pkg.Assets, version)
+		if pkg.Previous != nil && pkg.Previous.Version == version {
+			pkg.Previous = nil
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+// storeKept reports whether the version of the package is kept in the store
+// from the asset for any prefix, along with the digest of the asset when it is
+// known. A version whose asset has changed since is not kept.
+func storeKept(name string, version string, asset provider.Asset) (bool, string, error) {
+	if !path.Exists(path.StorePath(name, version, asset.Name)) {
+		return false, "", nil
+	}
+	kept, digest, err := state.Stored(name, version, asset.Name)
+	if err != nil || !kept {
+		return false, "", err
+	}
+	if digest != "" && asset.Digest != "" && digest != asset.Digest {
+		return false, "", nil
+	}
+	return true, digest, nil
+}
+
+// removeStoreVersion removes a version of the package installed from the
+// asset from the store unless the package is installed into another prefix
+// which also keeps it.
+func removeStoreVersion(key state.Key, version string, asset string) error {
+	pkgs, err := state.GetAll()
+	if err != nil {
+		return err
+	}
+	for other, pkg := range *pkgs {
+		if other.Name == key.Name && other.Prefix != key.Prefix &&
+			slices.Contains(pkg.Versions, version) && pkg.Assets[version] == asset {
+			log.Debugf("version %s of '%s' is still used in '%s'\n", version,
+				key.Name, path.Contract(other.Prefix))
+			return nil
+		}
+	}
+	return path.RemoveStore(path.StorePath(key.Name, version, asset))
+}
@@ -3,13 +3,26 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
+	"tuck/internal/config"
 	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/state"
 
 	"github.com/spf13/cobra"
 )
 
 var params struct {
 	Verbose int
+	Wait    string
+	Output  string
+	Profile string
+	Prefix  string
+	Config  string
+	System  bool
+	Root    string
+	OS      string
+	Arch    string
 }
 
 var rootCmd = &cobra.Command{
@@ -21,7 +34,24 @@ Tuck has the following goals:
 
 * Manage installation of local packages, similar to GNU Stow
 * Easily download and install packages from GitHub releases
-* Be a single statically compiled binary that's easy to install `,
+* Be a single statically compiled binary that's easy to install
+
+Packages are installed into the prefix given by --prefix, otherwise the prefix
+of the profile selected by --profile, TUCK_PREFIX, the default profile, prefix
+in tuck.yaml or ~/.local, in that order. The config is read from --config,
+TUCK_CONFIG or tuck.yaml in the XDG config directory. The cache and state
+directories follow XDG unless TUCK_CACHE_DIR or TUCK_STATE_DIR are set.
+
+With --system tuck installs into /usr/local for every user of the host, with
+its state and store in /var/lib/tuck, its cache in /var/cache/tuck and its
+config in /etc/tuck/tuck.yaml, and must be run as root. Installed files are
+owned by root with the mode 0755 for executables and 0644 for other files.
+
+With --root every prefix is inside the given directory, such as the root
+filesystem of a container image, which also holds the state and store in
+<root>/var/lib/tuck recording paths as seen from inside it, and packages are
+installed into /usr/local by default. Use --os and --arch to install packages
+for another platform than the host, e.g. to prepare an arm64 image.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		switch params.Verbose {
 		case 0:
@@ -31,12 +61,93 @@ Tuck has the following goals:
 		default:
 			log.SetLevel(log.LevelDebug)
 		}
+
+		if err := validateOutput(); err != nil {
+			log.Fatalln(err)
+		}
+
+		isCompletion := cmd.Name() == "completion" ||
+			(cmd.HasParent() && cmd.Parent().Name() == "completion")
+		if params.System {
+			if err := path.UseSystem(); err != nil {
+				log.Fatalln(err)
+			}
+			if os.Geteuid() != 0 && !isCompletion {
+				log.Fatalln("--system requires root privileges, try again with sudo")
+			}
+			config.UseSystem()
+		}
+		if params.Root != "" {
+			path.UseRoot(path.Abs(path.Expand(params.Root)))
+			config.UseRoot()
+		}
+		if params.OS != "" {
+			config.OS = params.OS
+		}
+		if params.Arch != "" {
+			config.Arch = params.Arch
+		}
+		if params.Config != "" {
+			config.ConfigFile = path.Abs(path.Expand(params.Config))
+		}
+		if err := path.Init(); err != nil {
+			log.Fatalln(err)
+		}
+
+		// refuse to do anything when the record of installed packages is
+		// unreadable rather than risk making it worse, generating shell
+		// completion scripts does not touch it so is always allowed
+		if !isCompletion {
+			if err := state.Check(); err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		if cmd.Flags().Changed("wait") {
+			path.LockWait = true
+			if params.Wait != "0" {
+				timeout, err := time.ParseDuration(params.Wait)
+				if err != nil {
+					log.Fatalf("invalid --wait timeout: '%s'\n", params.Wait)
+				}
+				path.LockTimeout = timeout
+			}
+		}
 	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().CountVarP(&params.Verbose, "verbose", "v",
 		"enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&params.Wait, "wait", "",
+		"wait for another tuck process to finish, optionally with a timeout e.g. --wait=30s")
+	rootCmd.PersistentFlags().Lookup("wait").NoOptDefVal = "0"
+	rootCmd.PersistentFlags().StringVarP(&params.Output, "output", "o", OutputPlain,
+		"output format, one of: plain, table, json, yaml")
+	rootCmd.PersistentFlags().StringVarP(&params.Prefix, "prefix", "p", "",
+		"install prefix path, overriding the profile and TUCK_PREFIX")
+	rootCmd.PersistentFlags().StringVarP(&params.Profile, "profile", "P", "",
+		"profile in tuck.yaml selecting the prefix and filters")
+	rootCmd.PersistentFlags().StringVar(&params.Config, "config", "",
+		"config file path (default $TUCK_CONFIG or tuck.yaml in the config directory)")
+	rootCmd.PersistentFlags().BoolVar(&params.System, "system", false,
+		"manage packages installed system-wide into /usr/local, requires root")
+	rootCmd.PersistentFlags().StringVar(&params.Root, "root", "",
+		"install into and keep the state inside this directory, e.g. an image rootfs")
+	rootCmd.PersistentFlags().StringVar(&params.OS, "os", "",
+		"operating system to install packages for (default the host's)")
+	rootCmd.PersistentFlags().StringVar(&params.Arch, "arch", "",
+		"CPU architecture to install packages for (default the host's)")
+	rootCmd.MarkPersistentFlagDirname("root")
+	rootCmd.RegisterFlagCompletionFunc("os", cobra.FixedCompletions(
+		[]string{"linux", "darwin"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("arch", cobra.FixedCompletions(
+		[]string{"amd64", "arm64"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("config", cobra.FixedCompletions(
+		[]string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt))
+	rootCmd.RegisterFlagCompletionFunc("profile", profileCompletionFunc)
+	rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		outputFormats, cobra.ShellCompDirectiveNoFileComp))
 }
 
 func SetVersion(version string, commit string, date string) {
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+var useParams struct {
+	Package string
+	Version string
+}
+
+var useCmd = &cobra.Command{
+	Use:   "use package@version",
+	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Short: "Switch a package to another installed version",
+	Long: `Switch a package to another version kept in the store, the links in
+the prefix are atomically replaced with links to the files of the version.`,
+	ValidArgsFunction: useValidArgsFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		at := strings.LastIndex(args[0], "@")
+		if at == -1 {
+			log.Fatalf("missing version, expected package@version: '%s'\n", args[0])
+		}
+		useParams.Package = args[0][:at]
+		useParams.Version = args[0][at+1:]
+		log.Debugf("use: %+v\n", useParams)
+
+		unlock, err := path.AcquireLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		_, pkg, err := lookupPackage(useParams.Package)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if pkg == nil {
+			log.Fatalln("package not installed:", useParams.Package)
+		}
+		if pkg.Local {
+			log.Fatalln("local packages have no versions:", useParams.Package)
+		}
+		if pkg.Version == useParams.Version {
+			fmt.Printf("tuck is already using '%s' %s\n",
+				useParams.Package, useParams.Version)
+			return
+		}
+		if !slices.Contains(pkg.Versions, useParams.Version) {
+			log.Fatalf("version %s of '%s' is not installed, available versions: %s\n",
+				useParams.Version, useParams.Package, strings.Join(pkg.Versions, ", "))
+		}
+
+		target := *pkg
+		// the version is the release, a constraint of the current release
+		// may not allow it
+		target.Version, target.Release = useParams.Version, useParams.Version
+		// where the version came from is only known for the previous version
+		target.Source, target.Digest, target.Asset = "", "", pkg.Assets[useParams.Version]
+		target.Installed = time.Now()
+		if previous := pkg.Previous; previous != nil && previous.Version == useParams.Version {
+			target.Release, target.Installed = previous.Release, previous.Installed
+			target.Source, target.Digest, target.Asset =
+				previous.Source, previous.Digest, previous.Asset
+		}
+		if err := activate(useParams.Package, pkg, target); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("tuck switched '%s' to %s in '%s'\n", useParams.Package,
+			useParams.Version, path.Contract(pkg.Prefix))
+	},
+}
+
+// linkVersion links the files of a store directory into prefix then removes
+// the links of the previously active version which are no longer needed.
+func linkVersion(storeDir string, storeFiles []string, prefix string,
+	previous []string, dryRun bool) ([]string, error) {
+	files, err := path.LinkFiles(storeDir, storeFiles, prefix, dryRun)
+	if err != nil {
+		return files, err
+	}
+	if !dryRun {
+		path.UnlinkStale(previous, files)
+	}
+	return files, nil
+}
+
+// activate links the files of the target entry's version, which must be kept
+// in the store and allowed by the package's pin, in place of the current
+// entry's files and stores the target entry as the package state. The current
+// entry becomes the previous entry.
+func activate(name string, current *state.Package, target state.Package) error {
+	if err := checkPin(current.Pin, target.Version); err != nil {
+		return err
+	}
+	storeDir := current.StorePath(name, target.Version)
+	storeFiles, err := path.StoreFiles(storeDir)
+	if err != nil {
+		return err
+	}
+	files, err := linkVersion(storeDir, storeFiles, current.Prefix, current.Files, false)
+	if err != nil {
+		return err
+	}
+	target.Prefix = current.Prefix
+	target.Versions = current.Versions
+	target.Assets = current.Assets
+	target.Files = files
+	target.Checksums = checksumFiles(files)
+	target.Modes = modeFiles(files)
+	target.Previous = snapshot(*current)
+	target.Pin = current.Pin
+	return state.Install(name, target)
+}
+
+func useValidArgsFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	pkgs, err := state.GetAll()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	for key, pkg := range *pkgs {
+		for _, version := range pkg.Versions {
+			if !slices.Contains(completions, key.Name+"@"+version) {
+				completions = append(completions, key.Name+"@"+version)
+			}
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	rootCmd.AddCommand(useCmd)
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"tuck/internal/cache"
+	"tuck/internal/config"
+	"tuck/internal/log"
+	"tuck/internal/path"
+	"tuck/internal/provider"
+	"tuck/internal/state"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyParams struct {
+	Packages []string
+	Fix      bool
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [flags] [package...]",
+	Args:  cobra.MatchAll(cobra.OnlyValidArgs),
+	Short: "Check installed files match what was installed",
+	Long: `Check the files of installed packages, or only the given packages,
+against the checksums and modes recorded when they were installed. Files which
+are missing, modified, have changed permissions or type, or are symlinks to
+files which no longer exist are reported.
+
+With --fix damaged packages are reinstalled, remote packages from the cache or
+their source and local packages from their directory or archive.
+
+The exit code is 0 when every package is intact, or was fixed, and 1 otherwise.`,
+	ValidArgsFunction: installedValidArgsFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		verifyParams.Packages = args
+		log.Debugf("verify: %+v\n", verifyParams)
+
+		lock := path.AcquireSharedLock
+		if verifyParams.Fix {
+			lock = path.AcquireLock
+		}
+		unlock, err := lock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		pkgs := state.State{}
+		damaged := 0
+		if len(verifyParams.Packages) == 0 {
+			pkgs, err = selectedPackages()
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+		for _, name := range verifyParams.Packages {
+			key, pkg, err := lookupPackage(name)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if pkg == nil {
+				log.Errorln("package not installed:", name)
+				damaged++
+				continue
+			}
+			pkgs[key] = *pkg
+		}
+
+		var cfg config.Config
+		if verifyParams.Fix {
+			cfg, err = config.Load()
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		for _, key := range sortedKeys(pkgs) {
+			name, pkg := key.Name, pkgs[key]
+			problems, err := state.Verify(pkg)
+			if err != nil {
+				log.Errorf("failed to verify '%s': %v\n", name, err)
+				damaged++
+				continue
+			}
+			if len(problems) == 0 {
+				log.Infof("%s: ok\n", path.Contract(name))
+				continue
+			}
+
+			fmt.Printf("%s: damaged\n", path.Contract(name))
+			for _, problem := range problems {
+				fmt.Printf("  %s: %s\n", problem.Kind, path.Contract(problem.File))
+			}
+			if !verifyParams.Fix {
+				damaged++
+				continue
+			}
+			if err := fixPackage(name, pkg, problems, cfg); err != nil {
+				log.Errorf("failed to fix '%s': %v\n", name, err)
+				damaged++
+				continue
+			}
+			fmt.Printf("tuck reinstalled '%s'\n", path.Contract(name))
+		}
+
+		if damaged > 0 {
+			unlock()
+			os.Exit(1)
+		}
+	},
+}
+
+// fixPackage reinstalls a damaged package in place, the damaged files are
+// removed first so they can be replaced.
+func fixPackage(name string, pkg state.Package, problems []state.Problem, cfg config.Config) error {
+	for _, problem := range problems {
+		// a directory in place of a file is only removed when empty
+		if err := os.Remove(problem.File); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	files := []string{}
+	if pkg.Local && pkg.Archive != "" {
+		checksum, err := path.HashFile(pkg.Archive)
+		if err != nil {
+			return err
+		}
+		if checksum != pkg.Sha256 {
+			return fmt.Errorf("archive '%s' has changed since it was installed",
+				path.Contract(pkg.Archive))
+		}
+		files, err = extractAndStow(pkg.Archive, pkg.Prefix, false)
+		if err != nil {
+			return err
+		}
+	} else if pkg.Local {
+		if !path.IsDir(name) {
+			return fmt.Errorf("local package no longer exists")
+		}
+		var err error
+		files, err = path.Stow(name, pkg.Prefix, path.StowMode(pkg.Stow), false)
+		if err != nil {
+			return err
+		}
+	} else {
+		entry, err := cache.Find(pkg.Digest, pkg.Source, pkg.Version)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			fetched, err := fetchRelease(name, pkg, cfg)
+			if err != nil {
+				return err
+			}
+			entry = &fetched
+		}
+		storeDir := pkg.StorePath(name, pkg.Version)
+		storeFiles, err := installToStore(entry.Path(), storeDir, false)
+		if err != nil {
+			return err
+		}
+		files, err = linkVersion(storeDir, storeFiles, pkg.Prefix, pkg.Files, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	pkg.Files = files
+	pkg.Checksums = checksumFiles(files)
+	pkg.Modes = modeFiles(files)
+	return state.Install(name, pkg)
+}
+
+// fetchRelease downloads the asset of the installed version of a remote
+// package into the cache.
+func fetchRelease(name string, pkg state.Package, cfg config.Config) (cache.Entry, error) {
+	source, repo, err := provider.Lookup(name, cfg.Packages)
+	if err != nil {
+		return cache.Entry{}, err
+	}
+	release, err := source.GetRelease(repo, pkg.Version)
+	if err != nil {
+		return cache.Entry{}, err
+	}
+	asset, err := provider.Select(source, release, cfg.Filters)
+	if err != nil {
+		return cache.Entry{}, err
+	}
+	return fetchAsset(source, asset, cache.Entry{
+		Name:     asset.Name,
+		Url:      asset.Url,
+		Digest:   asset.Digest,
+		Package:  name,
+		Provider: source.Name(),
+		Release:  pkg.Release,
+		Version:  pkg.Version,
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().BoolVar(&verifyParams.Fix, "fix", false,
+		"reinstall damaged packages")
+}
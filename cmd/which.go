@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"tuck/internal/log"
+	"tuck/internal/path"
+
+	"github.com/spf13/cobra"
+)
+
+var whichCmd = &cobra.Command{
+	Use:   "which [flags] command",
+	Args:  cobra.ExactArgs(1),
+	Short: "Show which package provides a command",
+	Long: `Find a command in PATH, the same as which(1), and show which package
+installed it. Exits with an error when the command is not found or is not
+managed by tuck.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Debugf("which: %+v\n", args)
+
+		file, err := exec.LookPath(args[0])
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		unlock, err := path.AcquireSharedLock()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer unlock()
+
+		if !reportOwner(path.Abs(file)) {
+			unlock()
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whichCmd)
+}
@@ -7,14 +7,28 @@ import (
 	"strings"
 )
 
+var extensions = []string{".tar.gz", ".tgz", ".tar.xz", ".tar.bz2", ".tar", ".zip"}
+
+// IsArchive reports whether name has the extension of a supported archive.
+func IsArchive(name string) bool {
+	for _, ext := range extensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
 func Extract(archive string, outdir string) error {
 	switch {
-	case strings.HasSuffix(archive, ".tar.gz"):
+	case strings.HasSuffix(archive, ".tar.gz"), strings.HasSuffix(archive, ".tgz"):
 		return tar("xzf", archive, outdir)
 	case strings.HasSuffix(archive, ".tar.xz"):
 		return tar("xJf", archive, outdir)
 	case strings.HasSuffix(archive, ".tar.bz2"):
 		return tar("xjf", archive, outdir)
+	case strings.HasSuffix(archive, ".tar"):
+		return tar("xf", archive, outdir)
 	case strings.HasSuffix(archive, ".zip"):
 		return unzip(archive, outdir)
 	default:
@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"tuck/internal/path"
+)
+
+// Entry describes a downloaded asset kept in the cache. Assets are stored by
+// the sha256 digest of their content so identical downloads are only kept
+// once, the remaining fields record where the asset came from so it can be
+// found again without access to the provider.
+type Entry struct {
+	Digest   string    `json:"digest"`
+	Name     string    `json:"name"`
+	Url      string    `json:"url"`
+	Size     int64     `json:"size"`
+	Package  string    `json:"package"`
+	Provider string    `json:"provider"`
+	Release  string    `json:"release"`
+	Version  string    `json:"version"`
+	Added    time.Time `json:"added"`
+	Used     time.Time `json:"used"`
+}
+
+// mutex serializes reads and changes of the index between concurrent
+// downloads.
+var mutex sync.Mutex
+
+// downloads holds a mutex for each URL being downloaded.
+var downloads sync.Map
+
+func dir() string {
+	return filepath.Join(path.CacheDir, "downloads")
+}
+
+// PartialDir returns the directory to download the asset at url into before
+// it is added to the cache, it is the same for every attempt so that
+// interrupted downloads can be resumed.
+func PartialDir(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir(), "partial", hex.EncodeToString(sum[:8]))
+}
+
+// LockDownload waits until no other download of the asset at url is in
+// progress, the returned function releases it. Downloads of the same URL
+// would otherwise write into the same partial directory at once.
+func LockDownload(url string) func() {
+	value, _ := downloads.LoadOrStore(url, &sync.Mutex{})
+	lock := value.(*sync.Mutex)
+	lock.Lock()
+	return lock.Unlock
+}
+
+func indexPath() string {
+	return filepath.Join(dir(), "index.json")
+}
+
+// Path returns the location of the cached asset, e.g.
+// "<CacheDir>/downloads/sha256/<hex>/<name>", the file keeps its name so that
+// its archive type can be detected.
+func (e Entry) Path() string {
+	algorithm, checksum, _ := strings.Cut(e.Digest, ":")
+	return filepath.Join(dir(), algorithm, checksum, e.Name)
+}
+
+// load reads the index, mutex must be held.
+func load() ([]Entry, error) {
+	entries := []Entry{}
+	data, err := os.ReadFile(indexPath())
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return entries, err
+	}
+	err = json.Unmarshal(data, &entries)
+	return entries, err
+}
+
+// store writes the index to a temporary file renamed over the index, so that
+// it is never read partially written, mutex must be held.
+func store(entries []Entry) error {
+	if err := os.MkdirAll(dir(), os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir(), ".index-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), indexPath())
+}
+
+// List returns the cached assets, most recently used first, entries whose
+// file has gone missing are omitted.
+func List() ([]Entry, error) {
+	mutex.Lock()
+	entries, err := load()
+	mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.DeleteFunc(entries, func(e Entry) bool {
+		return !path.Exists(e.Path())
+	})
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return b.Used.Compare(a.Used)
+	})
+	return entries, nil
+}
+
+// Find returns the cached asset with the digest when it is known, otherwise
+// the URL and version of an asset must match as the content of a URL may
+// change between versions.
+func Find(digest string, url string, version string) (*Entry, error) {
+	entries, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if digest != "" && entry.Digest == digest {
+			return &entry, nil
+		}
+		if digest == "" && entry.Url == url && entry.Version == version {
+			return &entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindRelease returns the most recently used asset downloaded for the
+// release of a package, release is either "latest" or a tag which is matched
+// against both the requested release and resolved version of entries.
+func FindRelease(pkg string, release string) (*Entry, error) {
+	entries, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Package != pkg {
+			continue
+		}
+		if release == "latest" || entry.Release == release || entry.Version == release {
+			return &entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// Add moves the downloaded file into the cache and records the entry. When
+// the entry has a digest the file content must match it.
+func Add(file string, entry Entry) (Entry, error) {
+	checksum, err := path.HashFile(file)
+	if err != nil {
+		return entry, err
+	}
+	digest := "sha256:" + checksum
+	if entry.Digest != "" && entry.Digest != digest {
+		os.Remove(file)
+		return entry, fmt.Errorf("digest mismatch for '%s': expected %s, got %s",
+			entry.Name, entry.Digest, digest)
+	}
+	entry.Digest = digest
+	info, err := os.Stat(file)
+	if err != nil {
+		return entry, err
+	}
+	entry.Size = info.Size()
+	entry.Added = time.Now()
+	entry.Used = entry.Added
+
+	if err := os.MkdirAll(filepath.Dir(entry.Path()), os.ModePerm); err != nil {
+		return entry, err
+	}
+	if err := os.Rename(file, entry.Path()); err != nil {
+		return entry, err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	entries, err := load()
+	if err != nil {
+		return entry, err
+	}
+	entries = slices.DeleteFunc(entries, func(e Entry) bool {
+		return e.Digest == entry.Digest && e.Package == entry.Package
+	})
+	return entry, store(append(entries, entry))
+}
+
+// Touch records that the cached asset was used for the package of the entry,
+// which is recorded for that package as well when it was added for another so
+// that it is found by FindRelease.
+func Touch(entry Entry) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+	recorded := false
+	for i := range entries {
+		if entries[i].Digest == entry.Digest {
+			entries[i].Used = time.Now()
+			recorded = recorded || entries[i].Package == entry.Package
+		}
+	}
+	if !recorded {
+		entry.Used = time.Now()
+		entries = append(entries, entry)
+	}
+	return store(entries)
+}
+
+// Size returns the total size of the cached assets.
+func Size() (int64, error) {
+	entries, err := List()
+	if err != nil {
+		return 0, err
+	}
+	size := int64(0)
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !seen[entry.Digest] {
+			size += entry.Size
+			seen[entry.Digest] = true
+		}
+	}
+	return size, nil
+}
+
+// Clean removes the cached assets which have not been used within olderThan,
+// or all of them when olderThan is zero, and returns the removed entries.
+func Clean(olderThan time.Duration) ([]Entry, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	entries, err := load()
+	if err != nil {
+		return nil, err
+	}
+	removed := []Entry{}
+	kept := []Entry{}
+	for _, entry := range entries {
+		if olderThan > 0 && time.Since(entry.Used) < olderThan {
+			kept = append(kept, entry)
+			continue
+		}
+		removed = append(removed, entry)
+	}
+	for _, entry := range removed {
+		if slices.ContainsFunc(kept, func(e Entry) bool { return e.Digest == entry.Digest }) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Dir(entry.Path())); err != nil {
+			return removed, err
+		}
+	}
+	return removed, store(kept)
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"tuck/internal/path"
+)
+
+func TestCache(t *testing.T) {
+	originalCacheDir := path.CacheDir
+	path.CacheDir = t.TempDir()
+	defer func() { path.CacheDir = originalCacheDir }()
+
+	download := filepath.Join(path.CacheDir, "tool.tar.gz")
+	if err := os.WriteFile(download, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Add(download, Entry{Name: "tool.tar.gz", Digest: "sha256:bad"}); err == nil {
+		t.Fatal("Add should fail when the digest does not match")
+	}
+
+	if err := os.WriteFile(download, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := Add(download, Entry{
+		Name:    "tool.tar.gz",
+		Url:     "https://example.com/tool.tar.gz",
+		Package: "tool",
+		Release: "latest",
+		Version: "1.0.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(entry.Path()); err != nil || string(data) != "archive" {
+		t.Fatalf("unexpected cached content %q: %v", data, err)
+	}
+
+	found, err := Find("", "https://example.com/tool.tar.gz", "1.0.0")
+	if err != nil || found == nil || found.Digest != entry.Digest {
+		t.Fatalf("Find by URL failed: %v %v", found, err)
+	}
+	if found, _ := Find("", "https://example.com/tool.tar.gz", "2.0.0"); found != nil {
+		t.Error("Find should not match the URL of another version")
+	}
+	if found, _ := FindRelease("tool", "1.0.0"); found == nil {
+		t.Error("FindRelease failed to find the version")
+	}
+
+	// reusing the asset for another package records it for that package
+	used := *found
+	used.Package, used.Release = "other", "1.0.0"
+	for range 2 {
+		if err := Touch(used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if found, _ := FindRelease("other", "latest"); found == nil || found.Digest != entry.Digest {
+		t.Errorf("FindRelease failed to find the asset reused for another package: %v", found)
+	}
+	if entries, _ := List(); len(entries) != 2 {
+		t.Errorf("expected an entry for each package, got %+v", entries)
+	}
+
+	removed, err := Clean(0)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("Clean removed %v: %v", removed, err)
+	}
+	if path.Exists(entry.Path()) {
+		t.Error("Clean did not remove the cached asset")
+	}
+}
+
+func TestConcurrentAdd(t *testing.T) {
+	originalCacheDir := path.CacheDir
+	path.CacheDir = t.TempDir()
+	defer func() { path.CacheDir = originalCacheDir }()
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 20)
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("tool-%d.tar.gz", i)
+			download := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(download, []byte(name), 0644); err != nil {
+				errs <- err
+				return
+			}
+			_, err := Add(download, Entry{Name: name, Package: name})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := List()
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 10 {
+		t.Errorf("expected 10 entries, got %d", len(entries))
+	}
+}
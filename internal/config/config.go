@@ -1,90 +1,341 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"tuck/internal/log"
 	"tuck/internal/path"
 
 	"go.yaml.in/yaml/v4"
 )
 
+// ConfigFile is tuck.yaml in the config directory unless TUCK_CONFIG names
+// another file, it is replaced by the --config flag.
 var (
-	ConfigFile = filepath.Join(path.ConfigDir, "tuck.yaml")
+	ConfigFile = cmp.Or(os.Getenv("TUCK_CONFIG"), filepath.Join(path.ConfigDir, "tuck.yaml"))
+	// defaultPrefix is used when the config has no prefix
+	defaultPrefix = DefaultPrefix
+	// OS and Arch are the Go names of the platform packages are installed
+	// for, the host unless overridden to prepare a root for another platform.
+	OS   = runtime.GOOS
+	Arch = runtime.GOARCH
+	// platformWarned is set once configured filters were found to select
+	// assets for the host rather than OS and Arch
+	platformWarned = false
 )
 
 // The filters below are used to select the release assets based on properties
 // of the localhost; required properties such as operating system and CPU
 // architecture, these must all match for an asset to be selected; optional
 // properties such as the linked C standard library, these will be used in the
-// event there are multiple candiate releases assets to choose from.
+// event there are multiple candiate releases assets to choose from. The
+// filters may refer to the platform packages are installed for as {{.OS}} and
+// {{.Arch}}, using the Go names, so that they follow --os and --arch, e.g.:
+//
+//	filters:
+//	  required: ["{{.OS}}", "{{.Arch}}"]
 type ConfigFilters struct {
 	Required []string `yaml:"required"`
 	Optional []string `yaml:"optional"`
 }
 
+// Packages which are not published as releases on a supported provider can
+// be described by a URL template, the template is expanded with the fields
+// Version, OS and Arch, where OS and Arch use the Go names such as "linux" and
+// "amd64", e.g.:
+//
+//	packages:
+//	  tool:
+//	    url: https://releases.example.com/{{.Version}}/tool_{{.Version}}_{{.OS}}_{{.Arch}}.zip
+//	    version_url: https://releases.example.com/LATEST
+//
+// The version installed when no release is requested is either the fixed
+// version, the first line of the response from version_url, or the latest
+// version discovered from a listing.
+type PackageConfig struct {
+	Url        string          `yaml:"url,omitempty"`
+	Version    string          `yaml:"version,omitempty"`
+	VersionUrl string          `yaml:"version_url,omitempty"`
+	Versions   *VersionsConfig `yaml:"versions,omitempty"`
+	Hooks      HooksConfig     `yaml:"hooks,omitempty"`
+}
+
+// Versions can be discovered from sources without a release API, such as an
+// HTTP directory listing or a JSON version index. Candidate versions are
+// extracted from the listing at url, either by matching regex against the
+// whole response or by applying selector to a JSON response and then
+// optionally matching regex against the selected values. When regex has a
+// capture group the first group is the version. The latest candidate
+// satisfying constraint is installed, e.g.:
+//
+//	packages:
+//	  go:
+//	    url: https://go.dev/dl/go{{.Version}}.{{.OS}}-{{.Arch}}.tar.gz
+//	    versions:
+//	      url: https://go.dev/dl/?mode=json
+//	      selector: "[].version"
+//	      regex: "go(.+)"
+//	      constraint: ">=1.22"
+//
+// The url of the package may be omitted when the listing is an HTML page
+// linking to the archives, in which case the links containing the version are
+// the candidate assets and are selected using the filters.
+type VersionsConfig struct {
+	Url        string `yaml:"url"`
+	Regex      string `yaml:"regex,omitempty"`
+	Selector   string `yaml:"selector,omitempty"`
+	Constraint string `yaml:"constraint,omitempty"`
+}
+
+// Hooks are shell commands run with sh before and after packages are
+// installed or removed, for every package or only for the package whose entry
+// in packages they are in, which needs no url for hooks alone, e.g.:
+//
+//	hooks:
+//	  post_install: hash -r
+//	packages:
+//	  junegunn/fzf:
+//	    hooks:
+//	      post_install: fzf --bash > ~/.local/share/bash-completion/completions/fzf
+//
+// Hooks are run with the environment variables TUCK_HOOK, TUCK_HOOK_PACKAGE,
+// TUCK_HOOK_PREFIX, TUCK_HOOK_VERSION and TUCK_HOOK_FILES, the newline
+// separated installed or removed files. A failing pre hook aborts the
+// operation, a failing post hook is only a warning. Hooks are killed after
+// timeout, a minute by default. Hooks run while tuck holds its lock, so they
+// can't run tuck on the same state themselves.
+type HooksConfig struct {
+	PreInstall  string        `yaml:"pre_install,omitempty"`
+	PostInstall string        `yaml:"post_install,omitempty"`
+	PreRemove   string        `yaml:"pre_remove,omitempty"`
+	PostRemove  string        `yaml:"post_remove,omitempty"`
+	Timeout     time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Profiles are named prefixes packages are installed into, selected with
+// --profile, each optionally with its own filters replacing the global
+// filters, e.g.:
+//
+//	profiles:
+//	  team:
+//	    prefix: /opt/team
+//	  musl:
+//	    prefix: ~/.local/musl
+//	    filters:
+//	      required: [linux, musl]
+//
+// The default profile installs into the prefix of the config unless it is
+// configured.
+type ProfileConfig struct {
+	Prefix  string         `yaml:"prefix"`
+	Filters *ConfigFilters `yaml:"filters,omitempty"`
+}
+
+// Prefix is where packages are installed when no profile is selected, the
+// prefix used is the first of:
+//
+//  1. the --prefix flag
+//  2. the prefix of the profile selected with --profile
+//  3. the TUCK_PREFIX environment variable
+//  4. the prefix of the default profile
+//  5. prefix in tuck.yaml
+//  6. ~/.local, or /usr/local with --system
+//
+// Retention is the number of versions of each package kept in the store,
+// including the active version, so that a package can be rolled back. Jobs is
+// the number of packages downloaded concurrently when installing several.
 type Config struct {
-	Filters ConfigFilters `yaml:"filters"`
+	Prefix    string                   `yaml:"prefix,omitempty"`
+	Filters   ConfigFilters            `yaml:"filters"`
+	Retention int                      `yaml:"retention"`
+	Jobs      int                      `yaml:"jobs"`
+	Hooks     HooksConfig              `yaml:"hooks,omitempty"`
+	Profiles  map[string]ProfileConfig `yaml:"profiles,omitempty"`
+	Packages  map[string]PackageConfig `yaml:"packages,omitempty"`
+}
+
+const (
+	DefaultRetention = 2
+	DefaultJobs      = 4
+	DefaultTimeout   = time.Minute
+	DefaultProfile   = "default"
+	DefaultPrefix    = "~/.local"
+	SystemPrefix     = "/usr/local"
+)
+
+// UseSystem reads the system-wide config and installs into SystemPrefix when
+// no prefix is configured, path.UseSystem must have been called first.
+func UseSystem() {
+	ConfigFile = filepath.Join(path.ConfigDir, "tuck.yaml")
+	defaultPrefix = SystemPrefix
 }
 
-func detectArchFilter() string {
-	switch runtime.GOARCH {
+// UseRoot installs into SystemPrefix inside path.Root when no prefix is
+// configured.
+func UseRoot() {
+	defaultPrefix = SystemPrefix
+}
+
+// Profile returns the named profile, or the default profile when name is
+// empty, with the global filters when the profile has none. The prefix of the
+// default profile is replaced by TUCK_PREFIX unless it was selected by name.
+func (c Config) Profile(name string) (ProfileConfig, error) {
+	selected := name != ""
+	if !selected {
+		name = DefaultProfile
+	}
+	profile, found := c.Profiles[name]
+	if !found && name != DefaultProfile {
+		return profile, fmt.Errorf("unknown profile: '%s'", name)
+	}
+	if name == DefaultProfile && profile.Prefix == "" {
+		profile.Prefix = cmp.Or(c.Prefix, defaultPrefix)
+	}
+	if prefix := os.Getenv("TUCK_PREFIX"); prefix != "" && !selected {
+		profile.Prefix = prefix
+	}
+	if profile.Prefix == "" {
+		return profile, fmt.Errorf("profile '%s' has no prefix", name)
+	}
+	if profile.Filters == nil {
+		profile.Filters = &c.Filters
+	}
+	return profile, nil
+}
+
+func detectArchFilter() (string, error) {
+	switch Arch {
 	case "amd64":
-		return "(amd64|x86-64|x86_64)"
+		return "(amd64|x86-64|x86_64)", nil
 	case "arm64":
-		return "(arm64|aarch64)"
+		return "(arm64|aarch64)", nil
 	default:
 		// TODO: Handle other architectures
-		log.Fatalln("unimplemented arch:", runtime.GOARCH)
-		return ""
+		return "", fmt.Errorf("unimplemented arch: %s, configure filters in '%s'",
+			Arch, ConfigFile)
 	}
 }
 
-func linuxDefaultFilters() ConfigFilters {
+func linuxDefaultFilters(arch string) ConfigFilters {
 	filters := ConfigFilters{}
 	filters.Required = append(filters.Required,
 		"linux",
 		"(.tar.(gz|bz2|xz)|.zip)$",
 	)
 	filters.Optional = append(filters.Optional,
-		detectArchFilter(),
+		arch,
 		"musl",
 	)
 	return filters
 }
 
-func darwinDefaultFilters() ConfigFilters {
+func darwinDefaultFilters(arch string) ConfigFilters {
 	filters := ConfigFilters{}
 	filters.Required = append(filters.Required,
 		"(mac|macos|darwin)",
 		"(.tar.(gz|bz2|xz)|.zip)$",
 	)
 	filters.Optional = append(filters.Optional,
-		detectArchFilter(),
+		arch,
 	)
 	return filters
 }
 
+// expand replaces {{.OS}} and {{.Arch}} in the filters.
+func (f *ConfigFilters) expand() error {
+	for _, filters := range [][]string{f.Required, f.Optional} {
+		for i, filter := range filters {
+			if !strings.Contains(filter, "{{") {
+				continue
+			}
+			tmpl, err := template.New("filter").Option("missingkey=error").Parse(filter)
+			if err != nil {
+				return fmt.Errorf("invalid filter '%s': %w", filter, err)
+			}
+			builder := strings.Builder{}
+			if err := tmpl.Execute(&builder, map[string]string{"OS": OS, "Arch": Arch}); err != nil {
+				return fmt.Errorf("invalid filter '%s': %w", filter, err)
+			}
+			filters[i] = builder.String()
+		}
+	}
+	return nil
+}
+
+// selectsPlatform reports whether the filters mention OS and Arch when they
+// differ from the host, filters written for the host would otherwise select
+// its assets.
+func (f ConfigFilters) selectsPlatform() bool {
+	filters := strings.Join(append(slices.Clone(f.Required), f.Optional...), " ")
+	return (OS == runtime.GOOS || strings.Contains(filters, OS)) &&
+		(Arch == runtime.GOARCH || strings.Contains(filters, Arch))
+}
+
+// defaultFilters returns the filters for OS and Arch used when the config
+// file has none.
+func defaultFilters() (ConfigFilters, error) {
+	if OS != "linux" && OS != "darwin" {
+		return ConfigFilters{}, fmt.Errorf("unimplemented OS: %s, configure filters in '%s'",
+			OS, ConfigFile)
+	}
+	arch, err := detectArchFilter()
+	if err != nil {
+		return ConfigFilters{}, err
+	}
+	if OS == "darwin" {
+		return darwinDefaultFilters(arch), nil
+	}
+	return linuxDefaultFilters(arch), nil
+}
+
 func Load() (Config, error) {
-	config := Config{}
+	config := Config{Retention: DefaultRetention, Jobs: DefaultJobs}
 	if path.Exists(ConfigFile) {
 		data, err := os.ReadFile(ConfigFile)
 		if err != nil {
 			return config, err
 		}
-		yaml.Unmarshal(data, &config)
-		// TODO: how to handle updating default filters?
-	} else {
-		switch runtime.GOOS {
-		case "linux":
-			config.Filters = linuxDefaultFilters()
-		case "darwin":
-			config.Filters = darwinDefaultFilters()
-		default:
-			return config, fmt.Errorf("unimplemented OS: %s", runtime.GOOS)
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("failed to parse '%s': %w", ConfigFile, err)
+		}
+		if config.Retention < 1 {
+			return config, fmt.Errorf("retention must keep at least 1 version: %d",
+				config.Retention)
+		}
+		filters := []*ConfigFilters{&config.Filters}
+		for _, profile := range config.Profiles {
+			if profile.Filters != nil {
+				filters = append(filters, profile.Filters)
+			}
+		}
+		for _, f := range filters {
+			if err := f.expand(); err != nil {
+				return config, err
+			}
+			configured := len(f.Required) > 0 || len(f.Optional) > 0
+			if configured && !f.selectsPlatform() && !platformWarned {
+				log.Warnf("the filters in '%s' don't select %s/%s assets, "+
+					"use {{.OS}} and {{.Arch}} in them to follow --os and --arch\n",
+					ConfigFile, OS, Arch)
+				platformWarned = true
+			}
+		}
+	}
+	// filters present in the config file replace the defaults, which are only
+	// known for some platforms
+	if len(config.Filters.Required) == 0 && len(config.Filters.Optional) == 0 {
+		filters, err := defaultFilters()
+		if err != nil {
+			return config, err
 		}
+		config.Filters = filters
 	}
 	return config, nil
 }
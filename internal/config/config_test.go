@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFilters(t *testing.T) {
+	oldFile, oldOS, oldArch := ConfigFile, OS, Arch
+	t.Cleanup(func() { ConfigFile, OS, Arch = oldFile, oldOS, oldArch })
+	ConfigFile = filepath.Join(t.TempDir(), "tuck.yaml")
+	OS, Arch = "freebsd", "riscv64"
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an error without default filters for the platform")
+	}
+
+	err := os.WriteFile(ConfigFile, []byte("filters:\n  required: [freebsd, riscv64]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Filters.Required) != 2 || len(config.Filters.Optional) != 0 {
+		t.Fatalf("expected the configured filters, got %+v", config.Filters)
+	}
+}
+
+func TestPlatformFilters(t *testing.T) {
+	oldFile, oldOS, oldArch := ConfigFile, OS, Arch
+	t.Cleanup(func() {
+		ConfigFile, OS, Arch = oldFile, oldOS, oldArch
+		platformWarned = false
+	})
+	ConfigFile = filepath.Join(t.TempDir(), "tuck.yaml")
+	OS, Arch = "linux", "riscv64"
+
+	err := os.WriteFile(ConfigFile, []byte(`filters:
+  required: ["{{.OS}}", "{{.Arch}}"]
+profiles:
+  musl:
+    prefix: /opt/musl
+    filters:
+      required: ["{{.OS}}-{{.Arch}}-musl"]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filters := config.Filters.Required; len(filters) != 2 ||
+		filters[0] != "linux" || filters[1] != "riscv64" {
+		t.Errorf("expected the platform in the filters, got %v", filters)
+	}
+	if filters := config.Profiles["musl"].Filters.Required; filters[0] != "linux-riscv64-musl" {
+		t.Errorf("expected the platform in the profile filters, got %v", filters)
+	}
+	if platformWarned {
+		t.Error("filters selecting the platform should not be warned about")
+	}
+
+	err = os.WriteFile(ConfigFile, []byte("filters:\n  required: [linux, x86_64]\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !platformWarned {
+		t.Error("filters for another architecture should be warned about")
+	}
+}
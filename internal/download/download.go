@@ -0,0 +1,293 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"tuck/internal/log"
+)
+
+// ConnectTimeout bounds establishing a connection and receiving the response
+// headers.
+const ConnectTimeout = 30 * time.Second
+
+var (
+	// ReadTimeout bounds the time waiting for more of the response body.
+	ReadTimeout = 60 * time.Second
+	// Retries is the number of times a download is retried after a transient
+	// error, with exponential backoff starting at RetryDelay.
+	Retries    = 4
+	RetryDelay = time.Second
+	// Quiet disables the progress display, e.g. when downloading concurrently.
+	Quiet = false
+)
+
+var client = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout: ConnectTimeout,
+		}).DialContext,
+		TLSHandshakeTimeout:   ConnectTimeout,
+		ResponseHeaderTimeout: ConnectTimeout,
+	},
+}
+
+// Options of a download, Size is the expected size of the file when known
+// and Header holds extra request headers such as authorization.
+type Options struct {
+	Size   int64
+	Header map[string]string
+}
+
+// statusError is an unsuccessful HTTP response, 5xx responses and rate
+// limiting are transient and retried.
+type statusError struct {
+	url       string
+	status    int
+	challenge string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("error downloading '%s': %d", e.url, e.status)
+}
+
+// Unauthorized returns the WWW-Authenticate challenge when err is an
+// unauthorized response, e.g. because a bearer token expired.
+func Unauthorized(err error) (string, bool) {
+	var status *statusError
+	if errors.As(err, &status) && status.status == http.StatusUnauthorized {
+		return status.challenge, true
+	}
+	return "", false
+}
+
+func (e *statusError) transient() bool {
+	return e.status >= 500 || e.status == http.StatusTooManyRequests ||
+		e.status == http.StatusRequestTimeout
+}
+
+// File downloads url to outpath. The content is written to "<outpath>.part"
+// which is resumed with a range request if it is left by an earlier attempt,
+// then renamed to outpath once complete and its size matches the expected
+// size, so outpath never contains a truncated file.
+func File(url string, outpath string, opts Options) error {
+	part := outpath + ".part"
+	var err error
+	for attempt := 0; attempt <= Retries; attempt++ {
+		if attempt > 0 {
+			delay := RetryDelay << (attempt - 1)
+			log.Warnf("%v, retrying in %v\n", err, delay)
+			time.Sleep(delay)
+		}
+		err = fetch(url, part, opts)
+		var status *statusError
+		if err == nil || (errors.As(err, &status) && !status.transient()) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(part)
+	if err != nil {
+		return err
+	}
+	if opts.Size > 0 && info.Size() != opts.Size {
+		os.Remove(part)
+		os.Remove(validatorPath(part))
+		return fmt.Errorf("downloaded size of '%s' is %d bytes, expected %d",
+			filepath.Base(outpath), info.Size(), opts.Size)
+	}
+	log.Debugf("%d bytes written to '%s'\n", info.Size(), outpath)
+	os.Remove(validatorPath(part))
+	return os.Rename(part, outpath)
+}
+
+// validatorPath returns the file holding the ETag or Last-Modified of the
+// response the part file was downloaded from, so that it is only resumed with
+// the same content.
+func validatorPath(part string) string {
+	return part + ".validator"
+}
+
+// validator returns the value for If-Range identifying the content of the
+// response, weak ETags can't be used for ranges.
+func validator(response *http.Response) string {
+	if etag := response.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return response.Header.Get("Last-Modified")
+}
+
+// fetch performs a single attempt to download url into the part file,
+// continuing from the end of the existing content.
+func fetch(url string, part string, opts Options) error {
+	offset := int64(0)
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	if opts.Size > 0 && offset == opts.Size {
+		return nil
+	}
+	resumeFrom, _ := os.ReadFile(validatorPath(part))
+	if len(resumeFrom) == 0 {
+		// without a validator the content may have changed since
+		offset = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range opts.Header {
+		request.Header.Set(key, value)
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		request.Header.Set("If-Range", string(resumeFrom))
+	}
+	log.Debugln("GET", url)
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch response.StatusCode {
+	case http.StatusOK:
+		// the server ignored the range, the content changed or there was
+		// nothing to resume
+		offset = 0
+		flags |= os.O_TRUNC
+		if value := validator(response); value != "" {
+			if err := os.WriteFile(validatorPath(part), []byte(value), 0644); err != nil {
+				return err
+			}
+		} else {
+			os.Remove(validatorPath(part))
+		}
+	case http.StatusPartialContent:
+		log.Debugf("resuming '%s' from %d bytes\n", filepath.Base(part), offset)
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the part file is unusable, start over on the next attempt
+		os.Remove(part)
+		os.Remove(validatorPath(part))
+		return fmt.Errorf("unable to resume download of '%s'", url)
+	default:
+		return &statusError{url: url, status: response.StatusCode,
+			challenge: response.Header.Get("WWW-Authenticate")}
+	}
+
+	outfile, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+
+	total := opts.Size
+	if total == 0 && response.ContentLength > 0 {
+		total = offset + response.ContentLength
+	}
+	var writer io.Writer = outfile
+	if !Quiet && isTerminal(os.Stderr) {
+		bar := newProgress(filepath.Base(url), offset, total)
+		defer bar.finish()
+		writer = io.MultiWriter(outfile, bar)
+	}
+
+	// cancel the request when no data arrives within the read timeout
+	timer := time.AfterFunc(ReadTimeout, cancel)
+	defer timer.Stop()
+	_, err = io.Copy(writer, &timeoutReader{reader: response.Body, timer: timer})
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("download of '%s' timed out", url)
+	}
+	if err != nil {
+		return err
+	}
+	if response.ContentLength > 0 {
+		if info, err := outfile.Stat(); err == nil &&
+			info.Size() != offset+response.ContentLength {
+			return fmt.Errorf("download of '%s' ended early", url)
+		}
+	}
+	return nil
+}
+
+// timeoutReader resets the timer every time data is read.
+type timeoutReader struct {
+	reader io.Reader
+	timer  *time.Timer
+}
+
+func (r *timeoutReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(ReadTimeout)
+	}
+	return n, err
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progress draws a single line progress bar on stderr.
+type progress struct {
+	name    string
+	current int64
+	total   int64
+	drawn   time.Time
+}
+
+func newProgress(name string, current int64, total int64) *progress {
+	return &progress{name: name, current: current, total: total}
+}
+
+func (p *progress) Write(data []byte) (int, error) {
+	p.current += int64(len(data))
+	if time.Since(p.drawn) > 100*time.Millisecond {
+		p.draw()
+	}
+	return len(data), nil
+}
+
+func (p *progress) draw() {
+	p.drawn = time.Now()
+	if p.total <= 0 {
+		fmt.Fprintf(os.Stderr, "\r%s %s", p.name, strconv.FormatInt(p.current, 10))
+		return
+	}
+	const width = 30
+	filled := int(width * p.current / p.total)
+	filled = min(max(filled, 0), width)
+	bar := make([]byte, width)
+	for i := range bar {
+		if i < filled {
+			bar[i] = '='
+		} else {
+			bar[i] = ' '
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\r%s [%s] %3d%%", p.name, bar, 100*p.current/p.total)
+}
+
+func (p *progress) finish() {
+	p.draw()
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,122 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func init() {
+	RetryDelay = time.Millisecond
+	Quiet = true
+}
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	outpath := filepath.Join(t.TempDir(), "file")
+	if err := File(server.URL, outpath, Options{Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+	if data, _ := os.ReadFile(outpath); string(data) != content {
+		t.Errorf("unexpected content %q", data)
+	}
+	if _, err := os.Stat(outpath + ".part"); !os.IsNotExist(err) {
+		t.Error("part file was not renamed")
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	if err := File(server.URL, filepath.Join(t.TempDir(), "file"), Options{}); err == nil {
+		t.Fatal("download should fail")
+	}
+	if requests != 1 {
+		t.Errorf("client errors should not be retried, got %d requests", requests)
+	}
+}
+
+func TestResume(t *testing.T) {
+	etag := `"v1"`
+	ranges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" && r.Header.Get("If-Range") == etag {
+			ranges++
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	outpath := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(outpath+".part", []byte(content[:10]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(validatorPath(outpath+".part"), []byte(etag), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := File(server.URL, outpath, Options{Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outpath); string(data) != content {
+		t.Errorf("unexpected content %q", data)
+	}
+	if ranges != 1 {
+		t.Errorf("expected the download to be resumed, got %d range requests", ranges)
+	}
+
+	// the part file of content which has changed since is discarded
+	etag = `"v2"`
+	if err := os.WriteFile(outpath+".part", []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(validatorPath(outpath+".part"), []byte(`"v1"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := File(server.URL, outpath, Options{Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outpath); string(data) != content {
+		t.Errorf("unexpected content %q", data)
+	}
+}
+
+func TestSizeMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+
+	outpath := filepath.Join(t.TempDir(), "file")
+	if err := File(server.URL, outpath, Options{Size: 1000}); err == nil {
+		t.Fatal("download should fail when the size does not match")
+	}
+	if _, err := os.Stat(outpath); !os.IsNotExist(err) {
+		t.Error("a truncated download must not be left at the output path")
+	}
+}
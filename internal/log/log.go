@@ -1,9 +1,12 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
+	"sync"
 )
 
 type LogLevel int
@@ -19,6 +22,10 @@ const (
 var (
 	logger *log.Logger
 	Level  LogLevel
+
+	// warnings are kept to be included in the results of commands
+	warnings      []string
+	warningsMutex sync.Mutex
 )
 
 func Debugln(args ...any) {
@@ -46,12 +53,14 @@ func Infof(format string, args ...any) {
 }
 
 func Warnln(args ...any) {
+	addWarning(fmt.Sprintln(args...))
 	if Level <= LevelWarn {
 		logger.Println(append([]any{"warn:"}, args...)...)
 	}
 }
 
 func Warnf(format string, args ...any) {
+	addWarning(fmt.Sprintf(format, args...))
 	if Level <= LevelWarn {
 		logger.Printf("warn: "+format, args...)
 	}
@@ -81,6 +90,22 @@ func Fatalf(format string, args ...any) {
 	}
 }
 
+func addWarning(message string) {
+	warningsMutex.Lock()
+	defer warningsMutex.Unlock()
+	warnings = append(warnings, strings.TrimSpace(message))
+}
+
+// Warnings returns the warnings logged since it was last called, regardless
+// of the log level.
+func Warnings() []string {
+	warningsMutex.Lock()
+	defer warningsMutex.Unlock()
+	logged := warnings
+	warnings = nil
+	return logged
+}
+
 func SetOutput(out io.Writer, flag int) {
 	logger = log.New(out, "tuck ", flag)
 }
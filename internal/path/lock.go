@@ -1,31 +1,119 @@
 package path
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gofrs/flock"
 )
 
-// AcquireLock attempts to acquire a file lock to ensure only one instance of
-// tuck is running at a time. It returns a cleanup function that must be called
-// to release the lock, or an error if the lock could not be acquired.
-func AcquireLock() (func(), error) {
-	lockFile := filepath.Join(StateDir, "tuck.lock")
+var (
+	// LockWait enables waiting for the lock to be released by another
+	// instance of tuck instead of failing immediately.
+	LockWait = false
+	// LockTimeout bounds how long to wait for the lock, zero waits forever.
+	LockTimeout = time.Duration(0)
+	// Hook is the hook tuck is run from, see config.HooksConfig. The tuck
+	// running the hook holds the lock until the hook exits so it is never
+	// waited for.
+	Hook = os.Getenv("TUCK_HOOK")
+)
+
+const lockRetryDelay = 100 * time.Millisecond
+
+func lockPath() string {
+	return filepath.Join(StateDir, "tuck.lock")
+}
+
+// lockHolder describes this process to be recorded in the lock file.
+func lockHolder() string {
+	return fmt.Sprintf("%d %s", os.Getpid(), strings.Join(os.Args, " "))
+}
+
+// describeHolder returns a description of the process recorded in the lock
+// file for error messages.
+func describeHolder() string {
+	data, err := os.ReadFile(lockPath())
+	if err != nil || len(data) == 0 {
+		return "another tuck process"
+	}
+	pid, command, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	return fmt.Sprintf("process %s (%s)", pid, command)
+}
+
+func acquire(shared bool) (func(), error) {
+	lockFile := lockPath()
 	fileLock := flock.New(lockFile)
 
-	locked, err := fileLock.TryLock()
+	try := fileLock.TryLock
+	tryContext := fileLock.TryLockContext
+	if shared {
+		try = fileLock.TryRLock
+		tryContext = fileLock.TryRLockContext
+	}
+
+	var locked bool
+	var err error
+	if LockWait && Hook == "" {
+		ctx := context.Background()
+		if LockTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, LockTimeout)
+			defer cancel()
+		}
+		locked, err = tryContext(ctx, lockRetryDelay)
+		if err == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %v waiting for lock held by %s",
+				LockTimeout, describeHolder())
+		}
+	} else {
+		locked, err = try()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to acquire lock on %s: %w", lockFile, err)
 	}
 
+	if !locked && Hook != "" {
+		return nil, fmt.Errorf("tuck can't be run from a %s hook while the tuck "+
+			"running the hook holds the lock", Hook)
+	}
 	if !locked {
-		return nil, fmt.Errorf("could not acquire lock (is tuck already running?)")
+		return nil, fmt.Errorf("could not acquire lock held by %s "+
+			"(use --wait to wait for it)", describeHolder())
 	}
 
+	// record the holder so that others can report who holds the lock, the
+	// lock is on the file itself so rewriting its content does not affect it
+	holder := lockHolder()
+	os.WriteFile(lockFile, []byte(holder+"\n"), 0600)
+
 	unlock := func() {
+		if data, err := os.ReadFile(lockFile); err == nil &&
+			strings.TrimSpace(string(data)) == holder {
+			os.Truncate(lockFile, 0)
+		}
 		fileLock.Unlock()
 	}
 
 	return unlock, nil
 }
+
+// AcquireLock attempts to acquire an exclusive file lock to ensure only one
+// instance of tuck is modifying packages at a time. It returns a cleanup
+// function that must be called to release the lock, or an error if the lock
+// could not be acquired. When LockWait is set it waits for the lock to be
+// released, up to LockTimeout.
+func AcquireLock() (func(), error) {
+	return acquire(false)
+}
+
+// AcquireSharedLock acquires a shared file lock for commands which only read
+// the state, any number of readers may hold the lock but not at the same time
+// as an exclusive lock. It behaves the same as AcquireLock otherwise.
+func AcquireSharedLock() (func(), error) {
+	return acquire(true)
+}
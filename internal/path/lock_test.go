@@ -1,8 +1,12 @@
 package path
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAcquireLock(t *testing.T) {
@@ -50,3 +54,75 @@ func TestAcquireLock(t *testing.T) {
 		t.Errorf("Lock file does not exist at %s", lockPath)
 	}
 }
+
+func TestAcquireSharedLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalStateDir := StateDir
+	StateDir = tmpDir
+	defer func() { StateDir = originalStateDir }()
+
+	// Any number of shared locks may be held at once
+	unlock1, err := AcquireSharedLock()
+	if err != nil {
+		t.Fatalf("First shared lock acquisition failed: %v", err)
+	}
+	unlock2, err := AcquireSharedLock()
+	if err != nil {
+		t.Fatalf("Second shared lock acquisition failed: %v", err)
+	}
+
+	// An exclusive lock can not be acquired while shared locks are held, the
+	// error should describe the holder
+	_, err = AcquireLock()
+	if err == nil {
+		t.Fatal("Exclusive lock acquisition should have failed")
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("process %d", os.Getpid())) {
+		t.Errorf("Error does not describe the lock holder: %v", err)
+	}
+
+	// Waiting times out while the shared locks are held
+	LockWait = true
+	LockTimeout = 200 * time.Millisecond
+	defer func() { LockWait = false; LockTimeout = 0 }()
+	if _, err := AcquireLock(); err == nil {
+		t.Fatal("Waiting for the exclusive lock should have timed out")
+	}
+
+	// Waiting succeeds once the shared locks are released
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unlock1()
+		unlock2()
+	}()
+	LockTimeout = 5 * time.Second
+	unlock3, err := AcquireLock()
+	if err != nil {
+		t.Fatalf("Waiting for the exclusive lock failed: %v", err)
+	}
+	unlock3()
+}
+
+func TestLockFromHook(t *testing.T) {
+	originalStateDir := StateDir
+	StateDir = t.TempDir()
+	LockWait = true
+	Hook = "post_install"
+	defer func() {
+		StateDir = originalStateDir
+		LockWait = false
+		Hook = ""
+	}()
+
+	unlock, err := AcquireLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	// the lock held by the tuck running the hook is not waited for
+	_, err = AcquireSharedLock()
+	if err == nil || !strings.Contains(err.Error(), "post_install hook") {
+		t.Fatalf("expected an error about the hook, got %v", err)
+	}
+}
@@ -1,9 +1,9 @@
 package path
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,12 +12,26 @@ import (
 	"github.com/adrg/xdg"
 )
 
+// The cache and state directories can be moved with the environment variables
+// TUCK_CACHE_DIR and TUCK_STATE_DIR, otherwise they follow XDG.
 var (
-	CacheDir  = filepath.Join(xdg.CacheHome, "tuck")
+	CacheDir  = envDir("TUCK_CACHE_DIR", filepath.Join(xdg.CacheHome, "tuck"))
 	ConfigDir = filepath.Join(xdg.ConfigHome, "tuck")
-	StateDir  = filepath.Join(xdg.StateHome, "tuck")
+	DataDir   = filepath.Join(xdg.DataHome, "tuck")
+	StateDir  = envDir("TUCK_STATE_DIR", filepath.Join(xdg.StateHome, "tuck"))
+	// StoreDir holds a directory for each installed version of a package
+	StoreDir = filepath.Join(DataDir, "store")
 )
 
+// envDir returns the absolute path of the directory named by the environment
+// variable, or dir when it isn't set.
+func envDir(name string, dir string) string {
+	if value := os.Getenv(name); value != "" {
+		return Abs(Expand(value))
+	}
+	return dir
+}
+
 func Abs(path string) string {
 	abs, err := filepath.Abs(path)
 	if err != nil {
@@ -32,14 +46,43 @@ func Exists(path string) bool {
 }
 
 func IsDir(path string) bool {
-	info, err := os.Stat(StateDir)
-	if !os.IsNotExist(err) {
+	info, err := os.Stat(path)
+	if err != nil {
 		return false
 	}
-	if info == nil || !info.IsDir() {
-		return false
+	return info.IsDir()
+}
+
+// HashFile returns the hex encoded sha256 checksum of the file content.
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
 	}
-	return true
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// FileMode returns the type of the file with the permissions of its content,
+// for a symlink that is the permissions of the file it links to.
+func FileMode(path string) (os.FileMode, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	mode := info.Mode()
+	if mode&os.ModeSymlink != 0 {
+		target, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		mode = os.ModeSymlink | target.Mode().Perm()
+	}
+	return mode, nil
 }
 
 func Expand(path string) string {
@@ -56,34 +99,16 @@ func Contract(path string) string {
 	return path
 }
 
-func DownloadFile(url string, outpath string) error {
-	response, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("error downloading '%s': %d", url, response.StatusCode)
-	}
-	outfile, err := os.Create(outpath)
-	log.Debugf("created file '%s'", outpath)
-	if err != nil {
-		return err
-	}
-	defer outfile.Close()
-	bytes, err := io.Copy(outfile, response.Body)
-	log.Debugf("%d bytes written to '%s'", bytes, outpath)
-	return err
-}
-
-func init() {
-	if !Exists(CacheDir) {
-		if err := os.MkdirAll(CacheDir, os.ModePerm); err != nil {
-			log.Fatalln(err)
+// Init creates the directories tuck keeps its cache, store and state in with
+// DirMode, it must be called once the directories are final.
+func Init() error {
+	for _, dir := range []string{CacheDir, StoreDir, StateDir} {
+		if err := checkOwner(dir); err != nil {
+			return err
 		}
-	}
-	if !Exists(StateDir) {
-		if err := os.MkdirAll(StateDir, os.ModePerm); err != nil {
-			log.Fatalln(err)
+		if err := os.MkdirAll(dir, DirMode); err != nil {
+			return err
 		}
 	}
+	return nil
 }
@@ -0,0 +1,41 @@
+package path
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Root is the directory every destination is inside of, such as the root
+// filesystem of a container image being built, set with UseRoot.
+var Root = ""
+
+// UseRoot keeps the state and store inside root at the system-wide location
+// so that the root carries its own record of the packages installed into it,
+// it must be called before Init.
+func UseRoot(root string) {
+	Root = root
+	DataDir = Rooted("/var/lib/tuck")
+	StateDir = DataDir
+	StoreDir = filepath.Join(DataDir, "store")
+}
+
+// Rooted returns the location of an absolute path inside Root.
+func Rooted(path string) string {
+	if Root == "" {
+		return path
+	}
+	return filepath.Join(Root, path)
+}
+
+// Unrooted returns a path inside Root as it is seen from within Root, paths
+// outside Root are returned unchanged.
+func Unrooted(path string) string {
+	if Root == "" {
+		return path
+	}
+	rel, err := filepath.Rel(Root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return path
+	}
+	return filepath.Join("/", rel)
+}
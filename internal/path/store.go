@@ -0,0 +1,131 @@
+package path
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"tuck/internal/log"
+)
+
+// StorePath returns the directory in the store holding the given version of
+// a package installed from the asset, e.g.
+// "<StoreDir>/owner/repo/v1.2.3/tool-linux-amd64.tar.gz", so that prefixes
+// selecting different assets of a version don't share it. Without an asset it
+// is the directory of the version. Provider prefixes and URL schemes in the
+// package name become directories.
+func StorePath(name string, version string, asset string) string {
+	name = strings.ReplaceAll(name, "://", "/")
+	name = strings.ReplaceAll(name, ":", "/")
+	if version == "" {
+		version = "unknown"
+	}
+	components := []string{StoreDir}
+	for _, component := range strings.Split(name+"/"+version+"/"+asset, "/") {
+		switch component {
+		case "", ".", "..":
+			continue
+		default:
+			components = append(components, strings.ReplaceAll(component, "@", "_"))
+		}
+	}
+	return filepath.Join(components...)
+}
+
+// StoreFiles returns the files within a store directory.
+func StoreFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// LinkFiles creates a relative symlink in prefix for each of the files in
+// dir, at the same path relative to prefix as the file is relative to dir.
+// Existing links are atomically replaced so the prefix never lacks a file
+// which is present in both the old and new version of a package.
+func LinkFiles(dir string, files []string, prefix string, dryRun bool) ([]string, error) {
+	links := []string{}
+	for _, file := range files {
+		relfile, err := filepath.Rel(dir, file)
+		if err != nil {
+			return links, err
+		}
+		link := filepath.Join(prefix, relfile)
+		links = append(links, link)
+		if dryRun {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(link), os.ModePerm); err != nil {
+			return links, err
+		}
+		target, err := filepath.Rel(filepath.Dir(link), file)
+		if err != nil {
+			return links, err
+		}
+		tmp := link + ".tuck-new"
+		os.Remove(tmp)
+		if err := os.Symlink(target, tmp); err != nil {
+			return links, err
+		}
+		if err := os.Rename(tmp, link); err != nil {
+			os.Remove(tmp)
+			return links, err
+		}
+	}
+	return links, nil
+}
+
+// UnlinkStale removes the links in old which are not in current, only links
+// into the store are removed as anything else was not created by tuck.
+func UnlinkStale(old []string, current []string) {
+	for _, link := range old {
+		if slices.Contains(current, link) || !IsStoreLink(link) {
+			continue
+		}
+		if err := os.Remove(link); err != nil {
+			log.Warnln(err)
+		} else {
+			log.Infoln("removed:", link)
+		}
+	}
+}
+
+// IsStoreLink reports whether path is a symlink into the store.
+func IsStoreLink(path string) bool {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	rel, err := filepath.Rel(StoreDir, target)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// RemoveStore removes the store directory of a package version, along with
+// any parent directories left empty.
+func RemoveStore(dir string) error {
+	rel, err := filepath.Rel(StoreDir, dir)
+	if err != nil || strings.HasPrefix(rel, "..") || rel == "." {
+		return fmt.Errorf("not a store directory: '%s'", dir)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	for parent := filepath.Dir(dir); parent != StoreDir; parent = filepath.Dir(parent) {
+		if os.Remove(parent) != nil {
+			break
+		}
+	}
+	return nil
+}
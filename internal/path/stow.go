@@ -3,11 +3,27 @@ package path
 import (
 	"tuck/internal/log"
 
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
+	"syscall"
+)
+
+// StowMode determines how files are placed into the destination.
+type StowMode string
+
+const (
+	// StowMove renames files into the destination, falling back to copying
+	// then removing the source when they are on different devices.
+	StowMove StowMode = "move"
+	// StowCopy copies files into the destination leaving the source intact.
+	StowCopy StowMode = "copy"
+	// StowLink creates symlinks in the destination to the source files.
+	StowLink StowMode = "link"
 )
 
 func isStdDirLayout(dirs []os.DirEntry) bool {
@@ -39,7 +55,10 @@ func isExecutable(path string) bool {
 			return true
 		}
 	} else {
-		stat, _ := os.Stat(path)
+		stat, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
 		mode := stat.Mode().Perm()
 		return mode&0111 != 0
 	}
@@ -51,12 +70,83 @@ func isManPage(path string) bool {
 	return strings.HasSuffix(path, ".1")
 }
 
-func Stow(src string, dst string, dryRun bool) []string {
+// CopyFile copies src to dst preserving its mode and modification time, if
+// src is a symlink then dst is a symlink with the same target.
+func CopyFile(src string, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+
+	infile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer infile.Close()
+	outfile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(outfile, infile); err != nil {
+		outfile.Close()
+		return err
+	}
+	if err := outfile.Close(); err != nil {
+		return err
+	}
+	// the umask may have masked the permissions at creation
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// place puts the src file at dst according to the mode.
+func place(src string, dst string, mode StowMode) error {
+	switch mode {
+	case StowCopy:
+		if err := CopyFile(src, dst); err != nil || !System {
+			return err
+		}
+		// copies of files owned by the user are installed system-wide
+		return normalizeFile(dst)
+	case StowLink:
+		if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return os.Symlink(Abs(src), dst)
+	default:
+		err := os.Rename(src, dst)
+		if errors.Is(err, syscall.EXDEV) {
+			log.Debugf("'%s' is on a different device, copying instead\n", src)
+			if err := CopyFile(src, dst); err != nil {
+				return err
+			}
+			return os.Remove(src)
+		}
+		return err
+	}
+}
+
+// Stow places the files of the package in src into dst according to the mode
+// and returns their paths in dst, the files placed before an error are
+// returned with it.
+func Stow(src string, dst string, mode StowMode, dryRun bool) ([]string, error) {
 	stows := []string{}
 
 	entries, err := os.ReadDir(src)
 	if err != nil {
-		log.Fatalln(err)
+		return stows, err
 	}
 
 	if isStdDirLayout(entries) {
@@ -71,15 +161,21 @@ func Stow(src string, dst string, dryRun bool) []string {
 
 		for _, entry := range entries {
 			if entry.IsDir() {
-				filepath.WalkDir(filepath.Join(src, entry.Name()),
+				err := filepath.WalkDir(filepath.Join(src, entry.Name()),
 					func(path string, d os.DirEntry, err error) error {
+						if err != nil {
+							return err
+						}
 						if d.IsDir() {
 							dirs = append(dirs, path)
 						} else {
 							files = append(files, path)
 						}
-						return err
+						return nil
 					})
+				if err != nil {
+					return stows, err
+				}
 			}
 		}
 
@@ -87,14 +183,14 @@ func Stow(src string, dst string, dryRun bool) []string {
 		for _, indir := range dirs {
 			reldir, err := filepath.Rel(src, indir)
 			if err != nil {
-				log.Fatalln(err)
+				return stows, err
 			}
 			outdir := filepath.Join(dst, reldir)
 			if !Exists(outdir) {
 				if !dryRun {
 					err := os.MkdirAll(outdir, os.ModePerm)
 					if err != nil {
-						log.Fatalln(err)
+						return stows, err
 					}
 				}
 			}
@@ -104,13 +200,13 @@ func Stow(src string, dst string, dryRun bool) []string {
 		for _, infile := range files {
 			relfile, err := filepath.Rel(src, infile)
 			if err != nil {
-				log.Fatalln(err)
+				return stows, err
 			}
 			outfile := filepath.Join(dst, relfile)
 			if !dryRun {
-				err = os.Rename(infile, outfile)
+				err = place(infile, outfile, mode)
 				if err != nil {
-					log.Fatalln(err)
+					return stows, err
 				}
 			}
 			stows = append(stows, outfile)
@@ -122,14 +218,20 @@ func Stow(src string, dst string, dryRun bool) []string {
 		// recursively enumerate entries in src path
 		dirs := []string{}
 		files := []string{}
-		filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
 			if d.IsDir() {
 				dirs = append(dirs, path)
 			} else {
 				files = append(files, path)
 			}
-			return err
+			return nil
 		})
+		if err != nil {
+			return stows, err
+		}
 
 		// copy files of interest but not docs/license/etc
 		bins := []string{}
@@ -154,15 +256,15 @@ func Stow(src string, dst string, dryRun bool) []string {
 			if !dryRun {
 				err := os.MkdirAll(binDir, os.ModePerm)
 				if err != nil {
-					log.Fatalln(err)
+					return stows, err
 				}
 			}
 			for _, inbin := range bins {
 				outbin := filepath.Join(binDir, filepath.Base(inbin))
 				if !dryRun {
-					err := os.Rename(inbin, outbin)
+					err := place(inbin, outbin, mode)
 					if err != nil {
-						log.Fatalln(err)
+						return stows, err
 					}
 				}
 				stows = append(stows, outbin)
@@ -175,15 +277,15 @@ func Stow(src string, dst string, dryRun bool) []string {
 			if !dryRun {
 				err = os.MkdirAll(manDir, os.ModePerm)
 				if err != nil {
-					log.Fatalln(err)
+					return stows, err
 				}
 			}
 			for _, src := range manpages {
 				outbin := filepath.Join(manDir, filepath.Base(src))
 				if !dryRun {
-					err := os.Rename(src, outbin)
+					err := place(src, outbin, mode)
 					if err != nil {
-						log.Fatalln(err)
+						return stows, err
 					}
 				}
 				stows = append(stows, outbin)
@@ -191,5 +293,5 @@ func Stow(src string, dst string, dryRun bool) []string {
 		}
 	}
 
-	return stows
+	return stows, nil
 }
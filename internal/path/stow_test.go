@@ -0,0 +1,78 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStowCopy(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "bin"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	tool := filepath.Join(src, "bin", "tool")
+	if err := os.WriteFile(tool, []byte("tool"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(tool, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("tool", filepath.Join(src, "bin", "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Stow(src, dst, StowCopy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+
+	// the source must be left intact
+	if !Exists(tool) {
+		t.Fatal("source file was removed")
+	}
+	info, err := os.Stat(filepath.Join(dst, "bin", "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("expected mode 0750, got %o", info.Mode().Perm())
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("expected mtime %v, got %v", mtime, info.ModTime())
+	}
+	target, err := os.Readlink(filepath.Join(dst, "bin", "alias"))
+	if err != nil || target != "tool" {
+		t.Errorf("expected symlink to 'tool', got %q: %v", target, err)
+	}
+}
+
+func TestStowLink(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "bin"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	tool := filepath.Join(src, "bin", "tool")
+	if err := os.WriteFile(tool, []byte("tool"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Stow(src, dst, StowLink, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", files)
+	}
+	target, err := os.Readlink(files[0])
+	if err != nil || target != tool {
+		t.Errorf("expected symlink to %q, got %q: %v", tool, target, err)
+	}
+}
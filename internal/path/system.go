@@ -0,0 +1,66 @@
+package path
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// System is set when tuck manages the system-wide prefix with its state,
+// store and cache outside any user's home directory.
+var System = false
+
+// DirMode is the mode directories are created with, system-wide directories
+// must be readable by every user regardless of the umask of root.
+var DirMode os.FileMode = os.ModePerm
+
+// Modes of the files installed system-wide, executables are 0755 and other
+// files 0644 regardless of the modes recorded in the archive.
+const (
+	ExecMode os.FileMode = 0755
+	DataMode os.FileMode = 0644
+)
+
+// UseSystem switches to the system-wide directories, it must be called before
+// Init. It fails where there are no system-wide directories.
+func UseSystem() error {
+	if err := systemUmask(); err != nil {
+		return err
+	}
+	System = true
+	CacheDir = "/var/cache/tuck"
+	ConfigDir = "/etc/tuck"
+	DataDir = "/var/lib/tuck"
+	StateDir = "/var/lib/tuck"
+	StoreDir = filepath.Join(DataDir, "store")
+	DirMode = ExecMode
+	return nil
+}
+
+// Normalize gives everything under dir the ownership of the current user and
+// the modes ExecMode for directories and executables, and DataMode for other
+// files, ignoring the owners and modes they were extracted with.
+func Normalize(dir string) error {
+	return filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return normalizeFile(file)
+	})
+}
+
+// normalizeFile gives the file the ownership and mode Normalize would.
+func normalizeFile(file string) error {
+	if err := os.Lchown(file, os.Geteuid(), os.Getegid()); err != nil {
+		return err
+	}
+	info, err := os.Lstat(file)
+	if err != nil || info.Mode()&fs.ModeSymlink != 0 {
+		return err
+	}
+	mode := DataMode
+	if info.IsDir() || info.Mode().Perm()&0111 != 0 {
+		mode = ExecMode
+	}
+	return os.Chmod(file, mode)
+}
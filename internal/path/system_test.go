@@ -0,0 +1,93 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "bin"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte("tool"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("readme"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("bin/tool", filepath.Join(dir, "tool")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Normalize(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]os.FileMode{
+		"bin":      ExecMode,
+		"bin/tool": ExecMode,
+		"README":   DataMode,
+	} {
+		info, err := os.Stat(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != expected {
+			t.Errorf("%s: expected mode %o, got %o", file, expected, info.Mode().Perm())
+		}
+	}
+}
+
+func TestStowCopyNormalizes(t *testing.T) {
+	System = true
+	defer func() { System = false }()
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(src, "bin"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("tool"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "data"), []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Stow(src, dst, StowCopy, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]os.FileMode{
+		"bin/tool": ExecMode,
+		"bin/data": DataMode,
+	} {
+		info, err := os.Stat(filepath.Join(dst, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != expected {
+			t.Errorf("%s: expected mode %o, got %o", file, expected, info.Mode().Perm())
+		}
+	}
+}
+
+func TestCheckOwnerRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	root := t.TempDir()
+	if err := os.Chown(root, 1000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "var", "lib", "tuck")
+	if err := checkOwner(dir); err == nil {
+		t.Error("checkOwner should refuse a directory owned by another user")
+	}
+
+	Root = root
+	defer func() { Root = "" }()
+	if err := checkOwner(dir); err != nil {
+		t.Errorf("checkOwner should allow the root being built: %v", err)
+	}
+}
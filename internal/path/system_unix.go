@@ -0,0 +1,43 @@
+//go:build unix
+
+package path
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// systemUmask makes directories created later, e.g. in the prefix, world
+// readable.
+func systemUmask() error {
+	syscall.Umask(0022)
+	return nil
+}
+
+// checkOwner refuses to let root create files in a directory belonging to
+// another user, such as the XDG cache of the user running sudo, which they
+// would be unable to remove. Directories inside Root are exempt as the root
+// filesystem being built may well belong to that user.
+func checkOwner(dir string) error {
+	if os.Geteuid() != 0 || System || (Root != "" && Unrooted(dir) != dir) {
+		return nil
+	}
+	// the directory is created inside its closest existing parent
+	existing := dir
+	info, err := os.Stat(existing)
+	for os.IsNotExist(err) && filepath.Dir(existing) != existing {
+		existing = filepath.Dir(existing)
+		info, err = os.Stat(existing)
+	}
+	if err != nil {
+		return err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 {
+		return fmt.Errorf("refusing to write into '%s' owned by another user as root, "+
+			"run tuck as that user or use --system to keep its files system-wide",
+			Contract(existing))
+	}
+	return nil
+}
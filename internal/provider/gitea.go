@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+const codebergUrl = "https://codeberg.org"
+
+type giteaReleaseAsset struct {
+	Id                 int    `json:"id"`
+	Name               string `json:"name"`
+	Size               int    `json:"size"`
+	DownloadCount      int    `json:"download_count"`
+	CreatedAt          string `json:"created_at"`
+	Uuid               string `json:"uuid"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+}
+
+type giteaRelease struct {
+	Id          int                 `json:"id"`
+	TagName     string              `json:"tag_name"`
+	Name        string              `json:"name"`
+	Draft       bool                `json:"draft"`
+	Prerelease  bool                `json:"prerelease"`
+	CreatedAt   string              `json:"created_at"`
+	PublishedAt string              `json:"published_at"`
+	Assets      []giteaReleaseAsset `json:"assets"`
+}
+
+// Gitea provides releases from a Gitea or Forgejo instance, such as Codeberg,
+// which share the same API.
+type Gitea struct {
+	Url string
+}
+
+// NewGitea creates a Gitea provider for the instance at baseUrl.
+func NewGitea(baseUrl string) *Gitea {
+	return &Gitea{Url: baseUrl}
+}
+
+func (g *Gitea) Name() string {
+	return GiteaName
+}
+
+func (g *Gitea) headers() map[string]string {
+	headers := map[string]string{}
+	if token := os.Getenv("GITEA_TOKEN"); token != "" {
+		headers["Authorization"] = "token " + token
+	}
+	return headers
+}
+
+func (g *Gitea) GetRelease(repo string, release string) (Release, error) {
+	endpoint := ""
+	if release == "latest" {
+		endpoint = fmt.Sprintf("%s/api/v1/repos/%s/releases/latest", g.Url, repo)
+	} else {
+		endpoint = fmt.Sprintf("%s/api/v1/repos/%s/releases/tags/%s",
+			g.Url, repo, url.PathEscape(release))
+	}
+	response := giteaRelease{}
+	if err := getJSON(endpoint, g.headers(), &response); err != nil {
+		return Release{}, err
+	}
+	return response.release(), nil
+}
+
+func (r giteaRelease) release() Release {
+	result := Release{
+		Tag:         r.TagName,
+		Name:        r.Name,
+		PublishedAt: r.PublishedAt,
+		Prerelease:  r.Prerelease,
+	}
+	for _, asset := range r.Assets {
+		result.Assets = append(result.Assets, Asset{
+			Name: asset.Name,
+			Url:  asset.BrowserDownloadUrl,
+			Size: asset.Size,
+		})
+	}
+	return result
+}
+
+func (g *Gitea) ListReleases(repo string) ([]Release, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/releases?limit=%d", g.Url, repo, listLimit)
+	response := []giteaRelease{}
+	if err := getJSON(endpoint, g.headers(), &response); err != nil {
+		return nil, err
+	}
+	releases := []Release{}
+	for _, release := range response {
+		if !release.Draft {
+			releases = append(releases, release.release())
+		}
+	}
+	return releases, nil
+}
+
+func (g *Gitea) Download(asset Asset, outpath string) error {
+	return downloadAsset(asset, outpath, nil)
+}
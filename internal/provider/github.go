@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+const githubApiUrl = "https://api.github.com"
+
+type githubReleaseAsset struct {
+	Id                 int            `json:"id"`
+	Name               string         `json:"name"`
+	ContentType        string         `json:"content_type"`
+	Size               int            `json:"size"`
+	Digest             string         `json:"digest"`
+	State              string         `json:"state"`
+	Url                string         `json:"url"`
+	NodeId             string         `json:"node_id"`
+	DownloadCount      int            `json:"download_count"`
+	Label              string         `json:"label"`
+	Uploader           map[string]any `json:"uploader"`
+	BrowserDownloadUrl string         `json:"browser_download_url"`
+	CreatedAt          string         `json:"created_at"`
+	UpdatedAt          string         `json:"updated_at"`
+}
+
+type githubRelease struct {
+	Assets          []githubReleaseAsset `json:"assets"`
+	AssetsUrl       string               `json:"assets_url"`
+	Author          map[string]any       `json:"author"`
+	CreatedAt       string               `json:"created_at"`
+	Draft           bool                 `json:"draft"`
+	HtmlUrl         string               `json:"html_url"`
+	Id              int                  `json:"id"`
+	Name            string               `json:"name"`
+	NodeId          string               `json:"node_id"`
+	Prerelease      bool                 `json:"prerelease"`
+	PublishedAt     string               `json:"published_at"`
+	TagName         string               `json:"tag_name"`
+	TarballUrl      string               `json:"tarball_url"`
+	TargetCommitish string               `json:"target_commitish"`
+	UploadUrl       string               `json:"upload_url"`
+	ZipballUrl      string               `json:"zipball_url"`
+}
+
+// GitHub provides releases from github.com or a GitHub Enterprise instance.
+type GitHub struct {
+	ApiUrl string
+}
+
+// NewGitHub creates a GitHub provider using apiUrl, or the public GitHub API
+// when apiUrl is empty.
+func NewGitHub(apiUrl string) *GitHub {
+	if apiUrl == "" {
+		apiUrl = githubApiUrl
+	}
+	return &GitHub{ApiUrl: apiUrl}
+}
+
+func (g *GitHub) Name() string {
+	return GitHubName
+}
+
+func isGhLoggedIn() bool {
+	cmd := exec.Command("gh", "auth", "status")
+	response, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	type AuthStatus struct {
+		Hosts map[string]any
+	}
+	ghAuthStatus := AuthStatus{}
+	err = json.Unmarshal(response, &ghAuthStatus)
+	if len(ghAuthStatus.Hosts) == 0 {
+		return false
+	}
+	return true
+}
+
+// api requests the url of the GitHub API and decodes the response into v.
+func (g *GitHub) api(url string, v any) error {
+	if g.ApiUrl == githubApiUrl && isGhLoggedIn() {
+		// Prefer using gh when is authenticated
+		cmd := exec.Command("gh", "api", url)
+		cmd.Stderr = os.Stderr
+		response, err := cmd.Output()
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(response, v)
+	}
+	// Use raw http request as gh doesn't allow unauthenticated api
+	// requests, however this might get rate limited
+	headers := map[string]string{}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return getJSON(fmt.Sprintf("%s/%s", g.ApiUrl, url), headers, v)
+}
+
+func (g *GitHub) GetRelease(repo string, release string) (Release, error) {
+	response := githubRelease{}
+	var err error
+	if release == "latest" {
+		err = g.api(fmt.Sprintf("repos/%s/releases/latest", repo), &response)
+	} else {
+		err = g.api(fmt.Sprintf("repos/%s/releases/tags/%s", repo, release), &response)
+	}
+	if err != nil {
+		return Release{}, err
+	}
+	return response.release(), nil
+}
+
+func (r githubRelease) release() Release {
+	result := Release{
+		Tag:         r.TagName,
+		Name:        r.Name,
+		PublishedAt: r.PublishedAt,
+		Prerelease:  r.Prerelease,
+	}
+	for _, asset := range r.Assets {
+		result.Assets = append(result.Assets, Asset{
+			Name:   asset.Name,
+			Url:    asset.BrowserDownloadUrl,
+			Size:   asset.Size,
+			Digest: asset.Digest,
+		})
+	}
+	return result
+}
+
+func (g *GitHub) ListReleases(repo string) ([]Release, error) {
+	response := []githubRelease{}
+	err := g.api(fmt.Sprintf("repos/%s/releases?per_page=%d", repo, listLimit), &response)
+	if err != nil {
+		return nil, err
+	}
+	releases := []Release{}
+	for _, release := range response {
+		if !release.Draft {
+			releases = append(releases, release.release())
+		}
+	}
+	return releases, nil
+}
+
+func (g *GitHub) Download(asset Asset, outpath string) error {
+	return downloadAsset(asset, outpath, nil)
+}
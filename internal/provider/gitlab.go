@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+)
+
+const gitlabUrl = "https://gitlab.com"
+
+type gitlabReleaseLink struct {
+	Id             int    `json:"id"`
+	Name           string `json:"name"`
+	Url            string `json:"url"`
+	DirectAssetUrl string `json:"direct_asset_url"`
+	LinkType       string `json:"link_type"`
+}
+
+type gitlabRelease struct {
+	Name            string `json:"name"`
+	TagName         string `json:"tag_name"`
+	CreatedAt       string `json:"created_at"`
+	ReleasedAt      string `json:"released_at"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+	Assets          struct {
+		Count int                 `json:"count"`
+		Links []gitlabReleaseLink `json:"links"`
+	} `json:"assets"`
+}
+
+// GitLab provides releases from gitlab.com or a self-hosted GitLab instance.
+// Only the release links are considered as assets, the generated source
+// archives are never useful to install.
+type GitLab struct {
+	Url string
+}
+
+// NewGitLab creates a GitLab provider for the instance at baseUrl, or
+// gitlab.com when baseUrl is empty.
+func NewGitLab(baseUrl string) *GitLab {
+	if baseUrl == "" {
+		baseUrl = gitlabUrl
+	}
+	return &GitLab{Url: baseUrl}
+}
+
+func (g *GitLab) Name() string {
+	return GitLabName
+}
+
+func (g *GitLab) headers() map[string]string {
+	headers := map[string]string{}
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		headers["PRIVATE-TOKEN"] = token
+	}
+	return headers
+}
+
+func (g *GitLab) GetRelease(repo string, release string) (Release, error) {
+	project := url.PathEscape(repo)
+	endpoint := ""
+	if release == "latest" {
+		endpoint = fmt.Sprintf("%s/api/v4/projects/%s/releases/permalink/latest",
+			g.Url, project)
+	} else {
+		endpoint = fmt.Sprintf("%s/api/v4/projects/%s/releases/%s",
+			g.Url, project, url.PathEscape(release))
+	}
+	response := gitlabRelease{}
+	if err := getJSON(endpoint, g.headers(), &response); err != nil {
+		return Release{}, err
+	}
+	return response.release(), nil
+}
+
+func (r gitlabRelease) release() Release {
+	result := Release{
+		Tag:         r.TagName,
+		Name:        r.Name,
+		PublishedAt: r.ReleasedAt,
+		Prerelease:  r.UpcomingRelease,
+	}
+	for _, link := range r.Assets.Links {
+		assetUrl := link.DirectAssetUrl
+		if assetUrl == "" {
+			assetUrl = link.Url
+		}
+		// Link names are free form, prefer the file name from the URL when
+		// the name does not look like one
+		name := link.Name
+		if path.Ext(name) == "" {
+			if u, err := url.Parse(assetUrl); err == nil {
+				name = path.Base(u.Path)
+			}
+		}
+		result.Assets = append(result.Assets, Asset{
+			Name: name,
+			Url:  assetUrl,
+		})
+	}
+	return result
+}
+
+func (g *GitLab) ListReleases(repo string) ([]Release, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/releases?per_page=%d",
+		g.Url, url.PathEscape(repo), listLimit)
+	response := []gitlabRelease{}
+	if err := getJSON(endpoint, g.headers(), &response); err != nil {
+		return nil, err
+	}
+	releases := []Release{}
+	for _, release := range response {
+		releases = append(releases, release.release())
+	}
+	return releases, nil
+}
+
+func (g *GitLab) Download(asset Asset, outpath string) error {
+	return downloadAsset(asset, outpath, nil)
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// graphqlBatch is the number of repositories queried by a single request.
+const graphqlBatch = 50
+
+// ErrNoToken is returned by BatchReleases when there is no GitHub token, the
+// GraphQL API does not allow anonymous requests.
+var ErrNoToken = errors.New("no GitHub token available")
+
+type githubGraphqlRelease struct {
+	TagName      string `json:"tagName"`
+	Name         string `json:"name"`
+	PublishedAt  string `json:"publishedAt"`
+	IsPrerelease bool   `json:"isPrerelease"`
+	IsDraft      bool   `json:"isDraft"`
+}
+
+type githubGraphqlResponse struct {
+	Data map[string]*struct {
+		Releases struct {
+			Nodes []githubGraphqlRelease `json:"nodes"`
+		} `json:"releases"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// token returns a token for the GitHub API from the environment or from gh
+// when it is logged in to github.com.
+func (g *GitHub) token() string {
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	if g.ApiUrl == githubApiUrl {
+		if output, err := exec.Command("gh", "auth", "token").Output(); err == nil {
+			return strings.TrimSpace(string(output))
+		}
+	}
+	return ""
+}
+
+// graphqlUrl returns the GraphQL endpoint, GitHub Enterprise serves it
+// alongside the REST API at /api/v3.
+func (g *GitHub) graphqlUrl() string {
+	if base, found := strings.CutSuffix(g.ApiUrl, "/api/v3"); found {
+		return base + "/api/graphql"
+	}
+	return g.ApiUrl + "/graphql"
+}
+
+// BatchReleases lists the recent releases of many repositories, newest first,
+// with a GraphQL request per graphqlBatch repositories instead of a REST
+// request for each. Repositories which could not be queried, e.g. because
+// they don't exist, are missing from the result.
+func (g *GitHub) BatchReleases(repos []string) (map[string][]Release, error) {
+	token := g.token()
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	result := map[string][]Release{}
+	for start := 0; start < len(repos); start += graphqlBatch {
+		batch := repos[start:min(start+graphqlBatch, len(repos))]
+		if err := g.batchReleases(token, batch, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (g *GitHub) batchReleases(token string, repos []string, result map[string][]Release) error {
+	declarations := []string{}
+	fields := []string{}
+	variables := map[string]string{}
+	for i, repo := range repos {
+		owner, name, found := strings.Cut(repo, "/")
+		if !found {
+			return fmt.Errorf("invalid GitHub repository: '%s'", repo)
+		}
+		declarations = append(declarations, fmt.Sprintf("$o%d: String!, $n%d: String!", i, i))
+		fields = append(fields, fmt.Sprintf("r%d: repository(owner: $o%d, name: $n%d) { "+
+			"releases(first: %d, orderBy: {field: CREATED_AT, direction: DESC}) { "+
+			"nodes { tagName name publishedAt isPrerelease isDraft } } }", i, i, i, listLimit))
+		variables[fmt.Sprintf("o%d", i)] = owner
+		variables[fmt.Sprintf("n%d", i)] = name
+	}
+	query := fmt.Sprintf("query(%s) { %s }",
+		strings.Join(declarations, ", "), strings.Join(fields, " "))
+
+	response := githubGraphqlResponse{}
+	err := postJSON(g.graphqlUrl(), map[string]string{"Authorization": "Bearer " + token},
+		map[string]any{"query": query, "variables": variables}, &response)
+	if err != nil {
+		return err
+	}
+	if response.Data == nil && len(response.Errors) > 0 {
+		return fmt.Errorf("GitHub GraphQL error: %s", response.Errors[0].Message)
+	}
+
+	for i, repo := range repos {
+		repository := response.Data[fmt.Sprintf("r%d", i)]
+		if repository == nil {
+			continue
+		}
+		releases := []Release{}
+		for _, node := range repository.Releases.Nodes {
+			if node.IsDraft {
+				continue
+			}
+			releases = append(releases, Release{
+				Tag:         node.TagName,
+				Name:        node.Name,
+				PublishedAt: node.PublishedAt,
+				Prerelease:  node.IsPrerelease,
+			})
+		}
+		result[repo] = releases
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"tuck/internal/log"
+)
+
+// get performs a GET request of url with the given headers and returns the
+// body of the response.
+func get(url string, headers map[string]string) ([]byte, error) {
+	return send(http.MethodGet, url, headers, nil)
+}
+
+// send performs a request of url and returns the body of a successful response.
+func send(method string, url string, headers map[string]string, body io.Reader) ([]byte, error) {
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	log.Debugln(method, url)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error requesting '%s': %d", url, response.StatusCode)
+	}
+	return content, nil
+}
+
+// getJSON performs a GET request of url with the given headers and decodes
+// the JSON response into v.
+func getJSON(url string, headers map[string]string, v any) error {
+	headers = withDefaults(headers, map[string]string{"Accept": "application/json"})
+	body, err := get(url, headers)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// withDefaults returns a copy of headers with the defaults added for headers
+// which are not set, the map of the caller is never modified.
+func withDefaults(headers map[string]string, defaults map[string]string) map[string]string {
+	merged := maps.Clone(defaults)
+	maps.Copy(merged, headers)
+	return merged
+}
+
+// postJSON performs a POST request of url with the JSON encoding of body and
+// decodes the JSON response into v.
+func postJSON(url string, headers map[string]string, body any, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	headers = withDefaults(headers, map[string]string{"Accept": "application/json"})
+	headers["Content-Type"] = "application/json"
+	content, err := send(http.MethodPost, url, headers, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
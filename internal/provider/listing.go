@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"tuck/internal/config"
+	"tuck/internal/log"
+	"tuck/internal/version"
+)
+
+var hrefRegex = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+
+// selectJSON returns the string values in value at selector, a dot separated
+// path of object keys where "[]" selects every element of an array, e.g.
+// "releases[].tag_name".
+func selectJSON(value any, selector string) []string {
+	selector = strings.TrimPrefix(selector, ".")
+	if selector == "" {
+		switch v := value.(type) {
+		case string:
+			return []string{v}
+		case float64:
+			return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+		default:
+			return nil
+		}
+	}
+	if rest, found := strings.CutPrefix(selector, "[]"); found {
+		items, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		values := []string{}
+		for _, item := range items {
+			values = append(values, selectJSON(item, rest)...)
+		}
+		return values
+	}
+	end := strings.IndexAny(selector, ".[")
+	if end == -1 {
+		end = len(selector)
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	return selectJSON(object[selector[:end]], selector[end:])
+}
+
+// extractVersions returns the unique candidate versions in the body of a
+// listing.
+func extractVersions(cfg config.VersionsConfig, body []byte) ([]string, error) {
+	values := []string{string(body)}
+	if cfg.Selector != "" {
+		var document any
+		if err := json.Unmarshal(body, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %w", cfg.Url, err)
+		}
+		values = selectJSON(document, cfg.Selector)
+	}
+
+	re := versionRegex
+	if cfg.Regex != "" {
+		var err error
+		re, err = regexp.Compile(cfg.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version regex '%s': %w", cfg.Regex, err)
+		}
+	}
+
+	candidates := []string{}
+	for _, value := range values {
+		if cfg.Selector != "" && cfg.Regex == "" {
+			candidates = append(candidates, value)
+			continue
+		}
+		for _, match := range re.FindAllStringSubmatch(value, -1) {
+			candidate := match[0]
+			if len(match) > 1 {
+				candidate = match[1]
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+	slices.Sort(candidates)
+	return slices.Compact(candidates), nil
+}
+
+// extractLinks returns the assets linked to from an HTML listing whose names
+// contain the version.
+func extractLinks(listingUrl string, body []byte, ver string) []Asset {
+	base, err := url.Parse(listingUrl)
+	if err != nil {
+		return nil
+	}
+	assets := []Asset{}
+	for _, match := range hrefRegex.FindAllSubmatch(body, -1) {
+		link, err := base.Parse(string(match[1]))
+		if err != nil {
+			continue
+		}
+		name := path.Base(link.Path)
+		if !strings.Contains(name, ver) || strings.HasSuffix(link.Path, "/") {
+			continue
+		}
+		if slices.ContainsFunc(assets, func(asset Asset) bool {
+			return asset.Url == link.String()
+		}) {
+			continue
+		}
+		assets = append(assets, Asset{Name: name, Url: link.String()})
+	}
+	return assets
+}
+
+// discover fetches the listing described by cfg and returns the latest
+// version satisfying the configured constraint, or the requested release when
+// it is not "latest", along with any assets linked to for that version.
+func discover(cfg config.VersionsConfig, release string) (string, []Asset, error) {
+	body, err := get(cfg.Url, map[string]string{})
+	if err != nil {
+		return "", nil, err
+	}
+	candidates, err := extractVersions(cfg, body)
+	if err != nil {
+		return "", nil, err
+	}
+	log.Debugf("found %d candidate versions at '%s'\n", len(candidates), cfg.Url)
+
+	expr := cfg.Constraint
+	if release != "latest" {
+		expr = "=" + release
+	}
+	constraint, err := version.ParseConstraint(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	latest, err := version.Latest(candidates, constraint)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w at '%s'", err, cfg.Url)
+	}
+	log.Infoln("discovered version:", latest)
+
+	assets := []Asset{}
+	if cfg.Selector == "" {
+		assets = extractLinks(cfg.Url, body, latest)
+	}
+	return latest, assets, nil
+}
@@ -0,0 +1,316 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"tuck/internal/config"
+	"tuck/internal/download"
+	"tuck/internal/log"
+	"tuck/internal/path"
+)
+
+const (
+	OCIName   = "oci"
+	ociPrefix = "oci://"
+
+	ociImageIndex      = "application/vnd.oci.image.index.v1+json"
+	ociImageManifest   = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	ociTitleAnnotation   = "org.opencontainers.image.title"
+	ociVersionAnnotation = "org.opencontainers.image.version"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant"`
+	} `json:"platform"`
+}
+
+// ociManifest holds the fields of both image indexes and image manifests, the
+// media type determines which are present.
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Manifests     []ociDescriptor   `json:"manifests"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations"`
+}
+
+// OCI provides archives published as artifacts in an OCI registry, such as
+// ghcr.io, using the registry v2 HTTP API. The layers of the manifest
+// matching the host platform are the assets of the release.
+type OCI struct {
+	// Url of the registry, e.g. "https://ghcr.io"
+	Url string
+	// Reference is the tag or digest given in the package, if any
+	Reference string
+	token     string
+}
+
+// ParseOCI splits a package of the form "oci://registry/name[:tag|@digest]"
+// into a provider for the registry and the repository name.
+func ParseOCI(pkg string) (*OCI, string, error) {
+	ref := strings.TrimPrefix(pkg, ociPrefix)
+	host, name, found := strings.Cut(ref, "/")
+	if !found || host == "" || name == "" {
+		return nil, "", fmt.Errorf("invalid OCI reference: '%s'", pkg)
+	}
+	reference := ""
+	if before, digest, found := strings.Cut(name, "@"); found {
+		name, reference = before, digest
+	} else if i := strings.LastIndex(name, ":"); i != -1 {
+		name, reference = name[:i], name[i+1:]
+	}
+	return &OCI{Url: ociScheme(host) + "://" + host, Reference: reference}, name, nil
+}
+
+// ociScheme selects plain HTTP for registries on the local host, such as a
+// test registry, or when TUCK_OCI_INSECURE is set.
+func ociScheme(host string) string {
+	hostname := host
+	if u, err := url.Parse("//" + host); err == nil {
+		hostname = u.Hostname()
+	}
+	if hostname == "localhost" || hostname == "127.0.0.1" || hostname == "::1" ||
+		os.Getenv("TUCK_OCI_INSECURE") != "" {
+		return "http"
+	}
+	return "https"
+}
+
+func (o *OCI) Name() string {
+	return OCIName
+}
+
+// authenticate requests a bearer token as described by the challenge in the
+// WWW-Authenticate header of an unauthorized response. Credentials are only
+// sent when TUCK_OCI_USERNAME and TUCK_OCI_PASSWORD are set, otherwise an
+// anonymous token is requested.
+func (o *OCI) authenticate(challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("unsupported registry authentication: '%s'", scheme)
+	}
+	fields := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		fields[key] = strings.Trim(value, `"`)
+	}
+	realm, err := url.Parse(fields["realm"])
+	if err != nil || fields["realm"] == "" {
+		return fmt.Errorf("invalid registry authentication realm: '%s'", challenge)
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if value, found := fields[key]; found {
+			query.Set(key, value)
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	request, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	username := os.Getenv("TUCK_OCI_USERNAME")
+	password := os.Getenv("TUCK_OCI_PASSWORD")
+	if username != "" && password != "" {
+		request.SetBasicAuth(username, password)
+	}
+	log.Debugln("GET", realm.String())
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry authentication failed: %d", response.StatusCode)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return err
+	}
+	o.token = token.Token
+	if o.token == "" {
+		o.token = token.AccessToken
+	}
+	return nil
+}
+
+// do performs a GET request of the registry, authenticating and retrying
+// once when the registry responds that authorization is required.
+func (o *OCI) do(url string, accept []string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			request.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if o.token != "" {
+			request.Header.Set("Authorization", "Bearer "+o.token)
+		}
+		log.Debugln("GET", url)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			response.Body.Close()
+			challenge := response.Header.Get("WWW-Authenticate")
+			if err := o.authenticate(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			return nil, fmt.Errorf("error requesting '%s': %d", url, response.StatusCode)
+		}
+		return response, nil
+	}
+}
+
+func (o *OCI) getManifest(name string, reference string) (ociManifest, error) {
+	manifest := ociManifest{}
+	response, err := o.do(fmt.Sprintf("%s/v2/%s/manifests/%s", o.Url, name, reference),
+		[]string{ociImageIndex, ociImageManifest, dockerManifestList, dockerManifest})
+	if err != nil {
+		return manifest, err
+	}
+	defer response.Body.Close()
+	if err := json.NewDecoder(response.Body).Decode(&manifest); err != nil {
+		return manifest, err
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = response.Header.Get("Content-Type")
+	}
+	return manifest, nil
+}
+
+func isIndex(manifest ociManifest) bool {
+	switch manifest.MediaType {
+	case ociImageIndex, dockerManifestList:
+		return true
+	default:
+		return manifest.MediaType == "" && len(manifest.Manifests) > 0
+	}
+}
+
+// layerName names a layer by its title annotation, as set by tools like
+// oras, or otherwise by its digest with an extension for its media type so
+// that it can be extracted.
+func layerName(layer ociDescriptor) string {
+	if title := layer.Annotations[ociTitleAnnotation]; title != "" {
+		return title
+	}
+	name := strings.ReplaceAll(layer.Digest, ":", "-")
+	switch {
+	case strings.HasSuffix(layer.MediaType, "tar+gzip"),
+		strings.HasSuffix(layer.MediaType, "tar.gzip"):
+		return name + ".tar.gz"
+	case strings.HasSuffix(layer.MediaType, ".tar"):
+		return name + ".tar"
+	default:
+		return name
+	}
+}
+
+func (o *OCI) GetRelease(repo string, release string) (Release, error) {
+	reference := release
+	if release == "latest" && o.Reference != "" {
+		reference = o.Reference
+	}
+	manifest, err := o.getManifest(repo, reference)
+	if err != nil {
+		return Release{}, err
+	}
+
+	if isIndex(manifest) {
+		digest := ""
+		for _, entry := range manifest.Manifests {
+			if entry.Platform != nil && entry.Platform.OS == config.OS &&
+				entry.Platform.Architecture == config.Arch {
+				digest = entry.Digest
+				break
+			}
+		}
+		if digest == "" {
+			return Release{}, fmt.Errorf("no manifest for platform %s/%s in '%s:%s'",
+				config.OS, config.Arch, repo, reference)
+		}
+		log.Debugf("selected manifest %s for platform %s/%s\n",
+			digest, config.OS, config.Arch)
+		manifest, err = o.getManifest(repo, digest)
+		if err != nil {
+			return Release{}, err
+		}
+	}
+
+	result := Release{Tag: reference}
+	if ver := manifest.Annotations[ociVersionAnnotation]; ver != "" {
+		result.Tag = ver
+	}
+	for _, layer := range manifest.Layers {
+		result.Assets = append(result.Assets, Asset{
+			Name:   layerName(layer),
+			Url:    fmt.Sprintf("%s/v2/%s/blobs/%s", o.Url, repo, layer.Digest),
+			Size:   layer.Size,
+			Digest: layer.Digest,
+		})
+	}
+	if len(result.Assets) == 0 {
+		return Release{}, fmt.Errorf("manifest '%s:%s' has no layers", repo, reference)
+	}
+	return result, nil
+}
+
+// Download fetches the blob of the asset and verifies it matches the digest.
+func (o *OCI) Download(asset Asset, outpath string) error {
+	algorithm, expected, found := strings.Cut(asset.Digest, ":")
+	if !found || algorithm != "sha256" {
+		return fmt.Errorf("unsupported digest: '%s'", asset.Digest)
+	}
+	header := map[string]string{}
+	if o.token != "" {
+		header["Authorization"] = "Bearer " + o.token
+	}
+	err := downloadAsset(asset, outpath, header)
+	if challenge, unauthorized := download.Unauthorized(err); unauthorized {
+		// authenticate and retry once, the token may have expired
+		if err := o.authenticate(challenge); err != nil {
+			return err
+		}
+		header["Authorization"] = "Bearer " + o.token
+		err = downloadAsset(asset, outpath, header)
+	}
+	if err != nil {
+		return err
+	}
+	actual, err := path.HashFile(outpath)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		os.Remove(outpath)
+		return fmt.Errorf("digest mismatch for '%s': expected %s, got sha256:%s",
+			asset.Name, asset.Digest, actual)
+	}
+	return nil
+}
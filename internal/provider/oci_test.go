@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// newRegistry creates a minimal registry v2 server which requires a bearer
+// token and serves an image index for a single tag.
+func newRegistry(t *testing.T, blob []byte) *httptest.Server {
+	manifest, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociImageManifest,
+		"layers": []map[string]any{{
+			"mediaType":   "application/vnd.oci.image.layer.v1.tar+gzip",
+			"digest":      digestOf(blob),
+			"size":        len(blob),
+			"annotations": map[string]string{ociTitleAnnotation: "tool.tar.gz"},
+		}},
+	})
+	index, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociImageIndex,
+		"manifests": []map[string]any{{
+			"mediaType": ociImageManifest,
+			"digest":    "sha256:other",
+			"platform":  map[string]string{"os": "plan9", "architecture": "mips"},
+		}, {
+			"mediaType": ociImageManifest,
+			"digest":    digestOf(manifest),
+			"platform":  map[string]string{"os": runtime.GOOS, "architecture": runtime.GOARCH},
+		}},
+	})
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:org/tool:pull" {
+			http.Error(w, "bad scope", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"token": "secret"}`)
+	})
+	authorized := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+					`Bearer realm="%s/token",service="test",scope="repository:org/tool:pull"`,
+					server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		}
+	}
+	mux.HandleFunc("/v2/org/tool/manifests/{reference}", authorized(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.PathValue("reference") {
+			case "1.2":
+				w.Header().Set("Content-Type", ociImageIndex)
+				w.Write(index)
+			case digestOf(manifest):
+				w.Header().Set("Content-Type", ociImageManifest)
+				w.Write(manifest)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+	mux.HandleFunc("/v2/org/tool/blobs/{digest}", authorized(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("digest") != digestOf(blob) {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(blob)
+		}))
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOCI(t *testing.T) {
+	blob := []byte("archive")
+	server := newRegistry(t, blob)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	source, repo, err := Lookup("oci://"+host+"/org/tool:1.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Name() != OCIName || repo != "org/tool" {
+		t.Fatalf("unexpected provider %s for %q", source.Name(), repo)
+	}
+	release, err := source.GetRelease(repo, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "1.2" || len(release.Assets) != 1 ||
+		release.Assets[0].Name != "tool.tar.gz" {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+
+	outpath := filepath.Join(t.TempDir(), release.Assets[0].Name)
+	if err := source.Download(release.Assets[0], outpath); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outpath); string(data) != string(blob) {
+		t.Errorf("unexpected blob content %q", data)
+	}
+
+	// an expired token is renewed
+	source.(*OCI).token = "expired"
+	if err := source.Download(release.Assets[0], outpath); err != nil {
+		t.Fatalf("Download should authenticate again: %v", err)
+	}
+
+	corrupt := release.Assets[0]
+	corrupt.Digest = digestOf([]byte("other"))
+	if err := source.Download(corrupt, outpath); err == nil {
+		t.Error("Download should fail when the digest does not match")
+	}
+	if _, err := os.Stat(outpath); !os.IsNotExist(err) {
+		t.Error("Download should remove the blob when the digest does not match")
+	}
+}
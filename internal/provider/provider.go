@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"tuck/internal/download"
+)
+
+// Release is the provider independent description of a published release,
+// each provider converts the response from its API into this form.
+type Release struct {
+	Tag         string
+	Name        string
+	PublishedAt string
+	Prerelease  bool
+	Assets      []Asset
+}
+
+// Asset is a single downloadable file attached to a release.
+type Asset struct {
+	Name   string
+	Url    string
+	Size   int
+	Digest string
+}
+
+// Provider is a source of releases, such as GitHub or a GitLab instance.
+type Provider interface {
+	// Name identifies the provider kind, it is recorded in state so later
+	// operations on the package know where it came from.
+	Name() string
+	// GetRelease resolves the release of repo, release is either a tag or
+	// "latest".
+	GetRelease(repo string, release string) (Release, error)
+	// Download fetches the asset into outpath.
+	Download(asset Asset, outpath string) error
+}
+
+// Lister is implemented by providers which can list the recent releases of a
+// repository, newest first, up to listLimit releases.
+type Lister interface {
+	ListReleases(repo string) ([]Release, error)
+}
+
+const listLimit = 30
+
+const (
+	GitHubName = "github"
+	GitLabName = "gitlab"
+	GiteaName  = "gitea"
+)
+
+// Parse selects the provider for the package argument and returns it along
+// with the repository path in the form the provider expects. Packages are
+// either a slug such as "owner/repo", which defaults to GitHub, a slug with a
+// provider prefix such as "gitlab:owner/repo", or a URL to the repository in
+// which case the provider is selected by the host, e.g.
+// "https://codeberg.org/owner/repo". A prefix can be combined with a URL to
+// select the provider of a self-hosted instance, e.g.
+// "gitea:https://git.example.com/owner/repo".
+func Parse(pkg string) (Provider, string, error) {
+	kind := ""
+	if prefix, rest, found := strings.Cut(pkg, ":"); found {
+		switch prefix {
+		case GitHubName, GitLabName, GiteaName, "forgejo", "codeberg":
+			kind = prefix
+			pkg = rest
+		}
+	}
+
+	if !strings.Contains(pkg, "://") {
+		repo := strings.Trim(pkg, "/")
+		if strings.Count(repo, "/") < 1 {
+			return nil, "", fmt.Errorf("invalid package: '%s'", pkg)
+		}
+		switch kind {
+		case "", GitHubName:
+			return NewGitHub(""), repo, nil
+		case GitLabName:
+			return NewGitLab(""), repo, nil
+		case "codeberg":
+			return NewGitea(codebergUrl), repo, nil
+		default:
+			return nil, "", fmt.Errorf(
+				"%s packages require the URL of the instance: '%s'", kind, pkg)
+		}
+	}
+
+	u, err := url.Parse(pkg)
+	if err != nil {
+		return nil, "", err
+	}
+	repo := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if strings.Count(repo, "/") < 1 {
+		return nil, "", fmt.Errorf("invalid package URL: '%s'", pkg)
+	}
+	base := u.Scheme + "://" + u.Host
+
+	if kind == "" {
+		kind = detectKind(u.Hostname())
+	}
+	switch kind {
+	case GitHubName:
+		if u.Hostname() == "github.com" {
+			return NewGitHub(""), repo, nil
+		}
+		// GitHub Enterprise serves the API under /api/v3
+		return NewGitHub(base + "/api/v3"), repo, nil
+	case GitLabName:
+		return NewGitLab(base), repo, nil
+	case GiteaName, "forgejo", "codeberg":
+		return NewGitea(base), repo, nil
+	default:
+		return nil, "", fmt.Errorf("unable to detect provider for '%s', "+
+			"use a 'github:', 'gitlab:' or 'gitea:' prefix", pkg)
+	}
+}
+
+func detectKind(host string) string {
+	switch {
+	case host == "github.com":
+		return GitHubName
+	case host == "gitlab.com", strings.Contains(host, "gitlab"):
+		return GitLabName
+	case host == "codeberg.org", strings.Contains(host, "gitea"),
+		strings.Contains(host, "forgejo"):
+		return GiteaName
+	default:
+		return ""
+	}
+}
+
+// downloadAsset downloads the asset with the given extra request headers.
+func downloadAsset(asset Asset, outpath string, header map[string]string) error {
+	return download.File(asset.Url, outpath, download.Options{
+		Size:   int64(asset.Size),
+		Header: header,
+	})
+}
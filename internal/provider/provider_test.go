@@ -0,0 +1,391 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tuck/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		pkg  string
+		name string
+		repo string
+		url  string
+	}{
+		{"owner/repo", GitHubName, "owner/repo", githubApiUrl},
+		{"github:owner/repo", GitHubName, "owner/repo", githubApiUrl},
+		{"https://github.com/owner/repo", GitHubName, "owner/repo", githubApiUrl},
+		{"gitlab:group/sub/project", GitLabName, "group/sub/project", gitlabUrl},
+		{"https://gitlab.com/group/project.git", GitLabName, "group/project", gitlabUrl},
+		{"https://gitlab.example.com/group/project", GitLabName, "group/project", "https://gitlab.example.com"},
+		{"codeberg:owner/repo", GiteaName, "owner/repo", codebergUrl},
+		{"https://codeberg.org/owner/repo", GiteaName, "owner/repo", codebergUrl},
+		{"gitea:https://git.example.com/owner/repo", GiteaName, "owner/repo", "https://git.example.com"},
+		{"forgejo:https://git.example.com/owner/repo", GiteaName, "owner/repo", "https://git.example.com"},
+	}
+	for _, test := range tests {
+		source, repo, err := Parse(test.pkg)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.pkg, err)
+			continue
+		}
+		if source.Name() != test.name || repo != test.repo {
+			t.Errorf("Parse(%q) = %s %q, expected %s %q", test.pkg,
+				source.Name(), repo, test.name, test.repo)
+		}
+		url := ""
+		switch p := source.(type) {
+		case *GitHub:
+			url = p.ApiUrl
+		case *GitLab:
+			url = p.Url
+		case *Gitea:
+			url = p.Url
+		}
+		if url != test.url {
+			t.Errorf("Parse(%q) url = %q, expected %q", test.pkg, url, test.url)
+		}
+	}
+
+	for _, pkg := range []string{"repo", "gitea:owner/repo", "https://example.com/owner/repo"} {
+		if _, _, err := Parse(pkg); err == nil {
+			t.Errorf("Parse(%q) should have failed", pkg)
+		}
+	}
+}
+
+func newServer(t *testing.T) (*httptest.Server, *http.ServeMux) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download/tool-linux-amd64.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "archive")
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, mux
+}
+
+func checkRelease(t *testing.T, source Provider, repo string, release string, tag string) {
+	result, err := source.GetRelease(repo, release)
+	if err != nil {
+		t.Fatalf("GetRelease(%q, %q) failed: %v", repo, release, err)
+	}
+	if result.Tag != tag {
+		t.Errorf("expected tag %q, got %q", tag, result.Tag)
+	}
+	if len(result.Assets) != 1 || result.Assets[0].Name != "tool-linux-amd64.tar.gz" {
+		t.Fatalf("unexpected assets: %+v", result.Assets)
+	}
+
+	outpath := filepath.Join(t.TempDir(), result.Assets[0].Name)
+	if err := source.Download(result.Assets[0], outpath); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	data, err := os.ReadFile(outpath)
+	if err != nil || string(data) != "archive" {
+		t.Errorf("unexpected download content %q: %v", data, err)
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	var server *httptest.Server
+	release := func(tag string) string {
+		return fmt.Sprintf(`{"tag_name": %q, "assets": [{"name": "tool-linux-amd64.tar.gz",
+			"browser_download_url": "%s/download/tool-linux-amd64.tar.gz"}]}`,
+			tag, server.URL)
+	}
+	server, mux := newServer(t)
+	mux.HandleFunc("/repos/owner/repo/releases/latest",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, release("v2.0.0"))
+		})
+	mux.HandleFunc("/repos/owner/repo/releases/tags/v1.0.0",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, release("v1.0.0"))
+		})
+
+	source := NewGitHub(server.URL)
+	checkRelease(t, source, "owner/repo", "latest", "v2.0.0")
+	checkRelease(t, source, "owner/repo", "v1.0.0", "v1.0.0")
+}
+
+func TestGitLab(t *testing.T) {
+	var server *httptest.Server
+	release := func(tag string) string {
+		return fmt.Sprintf(`{"tag_name": %q, "assets": {"links": [{"name": "Linux binary",
+			"direct_asset_url": "%s/download/tool-linux-amd64.tar.gz"}]}}`,
+			tag, server.URL)
+	}
+	server, mux := newServer(t)
+	mux.HandleFunc("/api/v4/projects/{project}/releases/permalink/latest",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("project") != "group/project" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprint(w, release("v2.0.0"))
+		})
+	mux.HandleFunc("/api/v4/projects/{project}/releases/v1.0.0",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, release("v1.0.0"))
+		})
+
+	source := NewGitLab(server.URL)
+	checkRelease(t, source, "group/project", "latest", "v2.0.0")
+	checkRelease(t, source, "group/project", "v1.0.0", "v1.0.0")
+}
+
+func TestGitea(t *testing.T) {
+	var server *httptest.Server
+	release := func(tag string) string {
+		return fmt.Sprintf(`{"tag_name": %q, "assets": [{"name": "tool-linux-amd64.tar.gz",
+			"browser_download_url": "%s/download/tool-linux-amd64.tar.gz"}]}`,
+			tag, server.URL)
+	}
+	server, mux := newServer(t)
+	mux.HandleFunc("/api/v1/repos/owner/repo/releases/latest",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, release("v2.0.0"))
+		})
+	mux.HandleFunc("/api/v1/repos/owner/repo/releases/tags/v1.0.0",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, release("v1.0.0"))
+		})
+
+	source := NewGitea(server.URL)
+	checkRelease(t, source, "owner/repo", "latest", "v2.0.0")
+	checkRelease(t, source, "owner/repo", "v1.0.0", "v1.0.0")
+}
+
+func TestURL(t *testing.T) {
+	server, mux := newServer(t)
+	mux.HandleFunc("/LATEST", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1.2.3\n")
+	})
+	mux.HandleFunc("/1.2.3/tool.zip", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "archive")
+	})
+
+	source, _, err := Lookup("tool", map[string]config.PackageConfig{
+		"tool": {
+			Url:        server.URL + "/{{.Version}}/tool.zip",
+			VersionUrl: server.URL + "/LATEST",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := source.GetRelease("tool", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "1.2.3" || len(release.Assets) != 1 ||
+		release.Assets[0].Url != server.URL+"/1.2.3/tool.zip" {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+
+	pkg := server.URL + "/download/tool-linux-amd64.tar.gz"
+	source, _, err = Lookup(pkg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Name() != URLName {
+		t.Fatalf("expected url provider for %q, got %s", pkg, source.Name())
+	}
+	checkRelease(t, source, pkg, "latest", "")
+
+	// an entry with only hooks leaves the package to its provider
+	source, repo, err := Lookup("owner/repo", map[string]config.PackageConfig{
+		"owner/repo": {Hooks: config.HooksConfig{PostInstall: "true"}},
+	})
+	if err != nil || source.Name() != GitHubName || repo != "owner/repo" {
+		t.Fatalf("expected the GitHub provider, got %v %q (%v)", source, repo, err)
+	}
+}
+
+func TestURLPackage(t *testing.T) {
+	for pkg, expected := range map[string][2]string{
+		"https://example.com/v1.1.0/tool-1.1.0-linux-amd64.tar.gz": {
+			"https://example.com/v{{.Version}}/tool-{{.Version}}-linux-amd64.tar.gz", "1.1.0"},
+		"http://10.1.1.0:8080/tool-1.1.0.tar.gz?token=1.1.0": {
+			"http://10.1.1.0:8080/tool-{{.Version}}.tar.gz?token=1.1.0", "1.1.0"},
+		"https://example.com/11.1.0/tool-1.1.0.zip": {
+			"https://example.com/11.1.0/tool-{{.Version}}.zip", "1.1.0"},
+		"https://example.com/tool-linux-amd64.tar.gz": {
+			"https://example.com/tool-linux-amd64.tar.gz", ""},
+	} {
+		name, version := URLPackage(pkg)
+		if name != expected[0] || version != expected[1] {
+			t.Errorf("%s: expected %q %q, got %q %q", pkg, expected[0], expected[1], name, version)
+		}
+	}
+
+	// the name installs the version of the URL
+	pkg := "https://example.com/download/tool-1.1.0-linux-amd64.tar.gz"
+	name, version := URLPackage(pkg)
+	source, repo, err := Lookup(name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := source.GetRelease(repo, version)
+	if err != nil || len(release.Assets) != 1 || release.Assets[0].Url != pkg {
+		t.Fatalf("expected the asset at %s, got %+v (%v)", pkg, release, err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	server, mux := newServer(t)
+	mux.HandleFunc("/dl/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><pre>
+<a href="../">../</a>
+<a href="tool-1.9.0-linux-amd64.tar.gz">tool-1.9.0-linux-amd64.tar.gz</a>
+<a href="tool-1.10.0-darwin-arm64.tar.gz">tool-1.10.0-darwin-arm64.tar.gz</a>
+<a href="tool-1.10.0-linux-amd64.tar.gz">tool-1.10.0-linux-amd64.tar.gz</a>
+<a href="tool-2.0.0-rc.1-linux-amd64.tar.gz">tool-2.0.0-rc.1-linux-amd64.tar.gz</a>
+</pre></body></html>`)
+	})
+	mux.HandleFunc("/index.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"version": "go1.21.5"}, {"version": "go1.22.0"}, {"version": "go1.23rc1"}]`)
+	})
+
+	source := NewURL(config.PackageConfig{
+		Versions: &config.VersionsConfig{
+			Url:   server.URL + "/dl/",
+			Regex: `tool-(\d[^/"]*?)-(linux|darwin)`,
+		},
+	})
+	release, err := source.GetRelease("tool", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "1.10.0" || len(release.Assets) != 2 ||
+		release.Assets[1].Url != server.URL+"/dl/tool-1.10.0-linux-amd64.tar.gz" {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+	release, err = source.GetRelease("tool", "1.9.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "1.9.0" || len(release.Assets) != 1 {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+
+	// without a regex the platform isn't taken for a pre-release
+	source = NewURL(config.PackageConfig{
+		Versions: &config.VersionsConfig{Url: server.URL + "/dl/"},
+	})
+	release, err = source.GetRelease("tool", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "1.10.0" {
+		t.Fatalf("expected 1.10.0, got %+v", release)
+	}
+	plain := &URL{Template: server.URL + "/dl/tool-1.2.3-linux-amd64.tar.gz"}
+	if version, err := plain.latestVersion(); err != nil || version != "1.2.3" {
+		t.Fatalf("expected version 1.2.3, got %q (%v)", version, err)
+	}
+
+	source = NewURL(config.PackageConfig{
+		Url: server.URL + "/go{{.Version}}.{{.OS}}-{{.Arch}}.tar.gz",
+		Versions: &config.VersionsConfig{
+			Url:        server.URL + "/index.json",
+			Selector:   "[].version",
+			Regex:      "go(.+)",
+			Constraint: "<1.22",
+		},
+	})
+	release, err = source.GetRelease("go", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Tag != "1.21.5" || len(release.Assets) != 1 {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+}
+
+func TestGitHubList(t *testing.T) {
+	server, mux := newServer(t)
+	mux.HandleFunc("/repos/owner/repo/releases",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"tag_name": "v3.0.0", "draft": true},
+				{"tag_name": "v2.1.0-rc1", "prerelease": true}, {"tag_name": "v2.0.0"}]`)
+		})
+
+	releases, err := NewGitHub(server.URL).ListReleases("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 2 || releases[0].Tag != "v2.1.0-rc1" ||
+		!releases[0].Prerelease || releases[1].Tag != "v2.0.0" {
+		t.Errorf("unexpected releases: %+v", releases)
+	}
+}
+
+func TestGitHubBatch(t *testing.T) {
+	server, mux := newServer(t)
+	requests := 0
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body := struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		data := map[string]any{}
+		for key, owner := range body.Variables {
+			if !strings.HasPrefix(key, "o") {
+				continue
+			}
+			alias := "r" + key[1:]
+			if owner == "missing" {
+				data[alias] = nil
+				continue
+			}
+			data[alias] = map[string]any{"releases": map[string]any{"nodes": []any{
+				map[string]any{"tagName": "v1.0.0-" + body.Variables["n"+key[1:]]},
+				map[string]any{"tagName": "draft", "isDraft": true},
+			}}}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+
+	source := NewGitHub(server.URL)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+	if _, err := source.BatchReleases([]string{"owner/a"}); err != ErrNoToken {
+		t.Fatalf("expected no token error, got %v", err)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "secret")
+	repos := []string{"missing/repo"}
+	for i := range graphqlBatch + 1 {
+		repos = append(repos, fmt.Sprintf("owner/r%d", i))
+	}
+	result, err := source.BatchReleases(repos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+	if _, found := result["missing/repo"]; found || len(result) != graphqlBatch+1 {
+		t.Errorf("unexpected repositories in result: %d", len(result))
+	}
+	releases := result["owner/r7"]
+	if len(releases) != 1 || releases[0].Tag != "v1.0.0-r7" {
+		t.Errorf("unexpected releases: %+v", releases)
+	}
+}
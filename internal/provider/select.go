@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"tuck/internal/config"
+	"tuck/internal/log"
+)
+
+type assetMatch struct {
+	asset      Asset
+	matchCount int
+	rank       int
+}
+
+func makeRegexFilters(filters []string) []*regexp.Regexp {
+	regexFilters := []*regexp.Regexp{}
+	for _, filter := range filters {
+		regexFilters = append(regexFilters, regexp.MustCompile("(?i)"+filter))
+	}
+	return regexFilters
+}
+
+func matchAllFilters(assets []Asset, regexFilters []*regexp.Regexp) []Asset {
+	candidates := []Asset{}
+	// TODO: not sure this is actually matching all the filters...
+	for _, asset := range assets {
+		matchCount := 0
+		for _, re := range regexFilters {
+			if re.MatchString(asset.Name) {
+				matchCount++
+			}
+		}
+		if matchCount == len(regexFilters) {
+			candidates = append(candidates, asset)
+		}
+	}
+	return candidates
+}
+
+func matchAnyFilter(assets []Asset, regexFilters []*regexp.Regexp) []assetMatch {
+	candidates := []assetMatch{}
+	for _, asset := range assets {
+		matchCount := 0
+		rank := -1
+		for i, re := range regexFilters {
+			if re.MatchString(asset.Name) {
+				matchCount++
+				if rank == -1 {
+					rank = i
+				}
+			}
+		}
+		if matchCount > 0 {
+			candidates = append(candidates, assetMatch{
+				asset:      asset,
+				matchCount: matchCount,
+				rank:       rank,
+			})
+		}
+	}
+	return candidates
+}
+
+// Select chooses the asset of the release to install. Providers which resolve
+// the asset for the host themselves, a URL template or the platform manifest
+// of an OCI artifact, skip filtering when that results in a single asset.
+func Select(source Provider, release Release, filters config.ConfigFilters) (Asset, error) {
+	switch source.Name() {
+	case URLName, OCIName:
+		if len(release.Assets) == 1 {
+			return release.Assets[0], nil
+		}
+	}
+	return SelectAsset(release, filters)
+}
+
+func SelectAsset(release Release, filters config.ConfigFilters) (Asset, error) {
+	candidate := Asset{}
+	candidates := matchAllFilters(release.Assets,
+		makeRegexFilters(filters.Required))
+	log.Infof("found %d candiates matching required filters:\n", len(candidates))
+	for _, cand := range candidates {
+		log.Infof("  %s\n", cand.Name)
+	}
+	switch len(candidates) {
+	case 0:
+		return Asset{}, fmt.Errorf(
+			"no assets found matching the filters '%v'", filters)
+	case 1:
+		candidate = candidates[0]
+	default:
+		optionalMatches := matchAnyFilter(candidates,
+			makeRegexFilters(filters.Optional))
+
+		switch len(optionalMatches) {
+		case 0:
+			return Asset{}, fmt.Errorf("multiple assets matched the " +
+				"required filters but non matched the optional filters")
+		case 1:
+			candidate = optionalMatches[0].asset
+		default:
+			// Find the highest match count
+			highestMatchCount := 0
+			for _, match := range optionalMatches {
+				if match.matchCount > highestMatchCount {
+					highestMatchCount = match.matchCount
+				}
+			}
+
+			// Collect all candidates with the highest match count
+			bestCandidates := []assetMatch{}
+			for _, match := range optionalMatches {
+				if match.matchCount == highestMatchCount {
+					bestCandidates = append(bestCandidates, match)
+				}
+			}
+
+			if len(bestCandidates) == 1 {
+				candidate = bestCandidates[0].asset
+			} else {
+				// Tie-break with ranking
+				lowestRank := -1
+				for _, match := range bestCandidates {
+					if lowestRank == -1 || match.rank < lowestRank {
+						lowestRank = match.rank
+					}
+				}
+
+				tiebreakCandidates := []assetMatch{}
+				for _, match := range bestCandidates {
+					if match.rank == lowestRank {
+						tiebreakCandidates = append(tiebreakCandidates, match)
+					}
+				}
+
+				if len(tiebreakCandidates) == 1 {
+					candidate = tiebreakCandidates[0].asset
+				} else {
+					names := []string{}
+					for _, cand := range tiebreakCandidates {
+						names = append(names, cand.asset.Name)
+					}
+					return Asset{}, fmt.Errorf("multiple assets matched both "+
+						"the required and optional filters with the same priority:\n  %s\n",
+						strings.Join(names, "\n  "))
+				}
+			}
+		}
+	}
+	log.Infoln("selected release asset:", candidate.Name)
+	return candidate, nil
+}
@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+	"tuck/internal/archive"
+	"tuck/internal/config"
+)
+
+const URLName = "url"
+
+// URL provides a single archive from an arbitrary URL, the URL is a template
+// which is expanded with the version to install and the host platform.
+// Alternatively the archives are the links found in a version listing.
+type URL struct {
+	Template   string
+	Version    string
+	VersionUrl string
+	Versions   *config.VersionsConfig
+}
+
+// NewURL creates a URL provider from a package config.
+func NewURL(cfg config.PackageConfig) *URL {
+	return &URL{
+		Template:   cfg.Url,
+		Version:    cfg.Version,
+		VersionUrl: cfg.VersionUrl,
+		Versions:   cfg.Versions,
+	}
+}
+
+// IsArchiveURL reports whether pkg is a URL to an archive which can be
+// installed directly.
+func IsArchiveURL(pkg string) bool {
+	u, err := url.Parse(pkg)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return archive.IsArchive(u.Path)
+}
+
+// URLPackage returns the package name of an archive URL, the URL with the
+// version in its path replaced by "{{.Version}}" so that installing the URL of
+// another version upgrades the package, and the version. URLs without a
+// version are their own name with an empty version.
+func URLPackage(pkg string) (string, string) {
+	u, err := url.Parse(pkg)
+	if err != nil {
+		return pkg, ""
+	}
+	version := strings.TrimPrefix(versionRegex.FindString(path.Base(u.Path)), "v")
+	if version == "" {
+		return pkg, ""
+	}
+	// only the path is templated, the host may look like a version
+	start := strings.Index(pkg, "://") + len("://")
+	start += strings.Index(pkg[start:], "/")
+	end := len(pkg)
+	if query := strings.IndexAny(pkg[start:], "?#"); query >= 0 {
+		end = start + query
+	}
+	name := strings.Builder{}
+	name.WriteString(pkg[:start])
+	rest := pkg[start:end]
+	for {
+		i := strings.Index(rest, version)
+		if i < 0 {
+			break
+		}
+		after := rest[i+len(version):]
+		// 1.1.0 is not the version in 11.1.0 or 1.1.0.1
+		if (i > 0 && isVersionChar(rest[i-1])) ||
+			(len(after) > 0 && isDigit(after[0])) ||
+			(len(after) > 1 && after[0] == '.' && isDigit(after[1])) {
+			name.WriteString(rest[:i+1])
+			rest = rest[i+1:]
+			continue
+		}
+		name.WriteString(rest[:i] + "{{.Version}}")
+		rest = after
+	}
+	name.WriteString(rest + pkg[end:])
+	return name.String(), version
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isVersionChar(c byte) bool {
+	return isDigit(c) || c == '.'
+}
+
+// Lookup selects the provider for pkg, packages described in the config take
+// precedence, then OCI references and URLs to archives, then the providers
+// supported by Parse.
+func Lookup(pkg string, packages map[string]config.PackageConfig) (Provider, string, error) {
+	if cfg, found := packages[pkg]; found {
+		switch {
+		case cfg.Url != "" || cfg.Versions != nil:
+			return NewURL(cfg), pkg, nil
+		case cfg.Hooks == config.HooksConfig{}:
+			return nil, "", fmt.Errorf(
+				"package '%s' config requires either url or versions", pkg)
+		}
+		// an entry with only hooks doesn't change where the package comes from
+	}
+	if strings.HasPrefix(pkg, ociPrefix) {
+		return ParseOCI(pkg)
+	}
+	if IsArchiveURL(pkg) {
+		return &URL{Template: pkg}, pkg, nil
+	}
+	return Parse(pkg)
+}
+
+func (u *URL) Name() string {
+	return URLName
+}
+
+// versionRegex matches versions in file names, only well known pre-release
+// suffixes are included so the platform following the version is not, e.g.
+// 1.2.3 in tool-1.2.3-linux-amd64.tar.gz and 2.0.0-rc.1 in tool-2.0.0-rc.1.zip.
+var versionRegex = regexp.MustCompile(`v?\d+\.\d+(?:\.\d+)?(?:-(?:alpha|beta|rc|pre)(?:\.?\d+)*)?`)
+
+func (u *URL) latestVersion() (string, error) {
+	switch {
+	case u.Version != "":
+		return u.Version, nil
+	case u.VersionUrl != "":
+		body, err := get(u.VersionUrl, map[string]string{})
+		if err != nil {
+			return "", err
+		}
+		version, _, _ := strings.Cut(strings.TrimSpace(string(body)), "\n")
+		version = strings.TrimSpace(version)
+		if version == "" {
+			return "", fmt.Errorf("no version found at '%s'", u.VersionUrl)
+		}
+		return version, nil
+	case !strings.Contains(u.Template, "{{"):
+		// a plain URL, the version is only informational so guess it from
+		// the file name
+		return strings.TrimPrefix(
+			versionRegex.FindString(path.Base(u.Template)), "v"), nil
+	default:
+		return "", fmt.Errorf("url template requires a version, " +
+			"configure version or version_url or specify the release")
+	}
+}
+
+type urlTemplateData struct {
+	Version string
+	OS      string
+	Arch    string
+}
+
+func (u *URL) expand(version string) (string, error) {
+	tmpl, err := template.New("url").Option("missingkey=error").Parse(u.Template)
+	if err != nil {
+		return "", fmt.Errorf("invalid url template '%s': %w", u.Template, err)
+	}
+	builder := strings.Builder{}
+	err = tmpl.Execute(&builder, urlTemplateData{
+		Version: version,
+		OS:      config.OS,
+		Arch:    config.Arch,
+	})
+	if err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// GetRelease expands the template, repo is unused as the template fully
+// describes the location of the archive.
+func (u *URL) GetRelease(repo string, release string) (Release, error) {
+	if u.Versions != nil {
+		version, assets, err := discover(*u.Versions, release)
+		if err != nil {
+			return Release{}, err
+		}
+		if u.Template == "" {
+			if len(assets) == 0 {
+				return Release{}, fmt.Errorf("no archives for version '%s' "+
+					"are linked from '%s'", version, u.Versions.Url)
+			}
+			return Release{Tag: version, Assets: assets}, nil
+		}
+		release = version
+	}
+
+	version := release
+	if release == "latest" {
+		var err error
+		version, err = u.latestVersion()
+		if err != nil {
+			return Release{}, err
+		}
+	}
+	assetUrl, err := u.expand(version)
+	if err != nil {
+		return Release{}, err
+	}
+	parsed, err := url.Parse(assetUrl)
+	if err != nil {
+		return Release{}, err
+	}
+	return Release{
+		Tag: version,
+		Assets: []Asset{{
+			Name: path.Base(parsed.Path),
+			Url:  assetUrl,
+		}},
+	}, nil
+}
+
+func (u *URL) Download(asset Asset, outpath string) error {
+	return downloadAsset(asset, outpath, nil)
+}
@@ -2,53 +2,345 @@ package state
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 	"tuck/internal/path"
 )
 
+// Package is the record of an installed package. Version is the active version
+// of the package and Versions are all the versions kept in the store, in the
+// order they were installed. Previous is the entry of the package before the
+// active version last changed, used to roll back. Stow is how the files were
+// placed into the prefix, see path.StowMode, Checksums holds the sha256 of
+// each installed file's content at install time and Modes its type and
+// permissions, see path.FileMode. Asset is the name of the release asset the
+// active version was installed from and Installed is when it was installed.
+// Pin holds the package at its current version, or versions matching a
+// constraint, until it is unpinned. Assets maps the versions to the asset they
+// were installed from, which names their directory in the store.
 type Package struct {
-	Prefix  string   `json:"prefix"`
-	Release string   `json:"release"`
-	Local   bool     `json:"local"`
-	Files   []string `json:"files"`
+	Prefix    string                 `json:"prefix"`
+	Release   string                 `json:"release"`
+	Version   string                 `json:"version,omitempty"`
+	Versions  []string               `json:"versions,omitempty"`
+	Assets    map[string]string      `json:"assets,omitempty"`
+	Provider  string                 `json:"provider,omitempty"`
+	Source    string                 `json:"source,omitempty"`
+	Asset     string                 `json:"asset,omitempty"`
+	Digest    string                 `json:"digest,omitempty"`
+	Archive   string                 `json:"archive,omitempty"`
+	Sha256    string                 `json:"sha256,omitempty"`
+	Local     bool                   `json:"local"`
+	Stow      string                 `json:"stow,omitempty"`
+	Files     []string               `json:"files"`
+	Checksums map[string]string      `json:"checksums,omitempty"`
+	Modes     map[string]os.FileMode `json:"modes,omitempty"`
+	Previous  *Package               `json:"previous,omitempty"`
+	Installed time.Time              `json:"installed,omitzero"`
+	Pin       *Pin                   `json:"pin,omitempty"`
 }
 
-type State = map[string]Package
+// Pin holds a package at the versions matching Version, a version constraint
+// such as "=1.2.3" or "1.2", Reason tells others why.
+type Pin struct {
+	Version string    `json:"version"`
+	Reason  string    `json:"reason,omitempty"`
+	Pinned  time.Time `json:"pinned"`
+}
+
+// Key identifies an installed package, the same package may be installed
+// into several prefixes.
+type Key struct {
+	Prefix string `json:"prefix"`
+	Name   string `json:"name"`
+}
+
+type State = map[Key]Package
+
+// SchemaVersion is the version of the state file format written by this
+// build, older files are migrated when loaded.
+const SchemaVersion = 4
+
+// Backups is the number of previous state files kept, as installed.json.1 for
+// the most recent up to installed.json.<Backups>.
+const Backups = 3
+
+// document is the content of the state file, packages are stored by prefix
+// then name. Files is a reverse index from each installed file to the package
+// that owns it, rebuilt whenever the state is stored so that it always agrees
+// with the packages.
+type document struct {
+	SchemaVersion int                           `json:"schemaVersion"`
+	Packages      map[string]map[string]Package `json:"packages"`
+	Files         map[string]Key                `json:"files"`
+}
+
+// migrations upgrade the raw state file, migrations[i] upgrades a file with
+// schema version i+1 to version i+2.
+var migrations = []func(data []byte) ([]byte, error){
+	migrateV1,
+	migrateV2,
+	migrateV3,
+}
+
+// migrateV1 wraps the flat map of packages of the original format, which had
+// no schema version, in a document.
+func migrateV1(data []byte) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"packages":      json.RawMessage(data),
+	})
+}
+
+// migrateV2 adds the reverse index of files.
+func migrateV2(data []byte) ([]byte, error) {
+	doc := struct {
+		Packages map[string]Package `json:"packages"`
+	}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for name, pkg := range doc.Packages {
+		for _, file := range pkg.Files {
+			files[file] = name
+		}
+	}
+	return json.Marshal(map[string]any{
+		"schemaVersion": 3,
+		"packages":      doc.Packages,
+		"files":         files,
+	})
+}
+
+// migrateV3 stores packages by prefix so a package can be installed into
+// more than one prefix.
+func migrateV3(data []byte) ([]byte, error) {
+	doc := struct {
+		Packages map[string]Package `json:"packages"`
+	}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	state := State{}
+	for name, pkg := range doc.Packages {
+		state[Key{Prefix: pkg.Prefix, Name: name}] = pkg
+	}
+	return json.Marshal(newDocument(state))
+}
+
+func newDocument(state State) document {
+	doc := document{
+		SchemaVersion: SchemaVersion,
+		Packages:      map[string]map[string]Package{},
+		Files:         map[string]Key{},
+	}
+	for key, pkg := range state {
+		if doc.Packages[key.Prefix] == nil {
+			doc.Packages[key.Prefix] = map[string]Package{}
+		}
+		doc.Packages[key.Prefix][key.Name] = pkg
+		for _, file := range pkg.Files {
+			// a file installed by several packages belongs to the last
+			if other, found := doc.Files[file]; found &&
+				state[other].Installed.After(pkg.Installed) {
+				continue
+			}
+			doc.Files[file] = key
+		}
+	}
+	return doc
+}
+
+func (doc document) state() State {
+	state := State{}
+	for prefix, pkgs := range doc.Packages {
+		for name, pkg := range pkgs {
+			state[Key{Prefix: prefix, Name: name}] = pkg
+		}
+	}
+	return state
+}
+
+// reroot returns the packages with their prefixes and installed files mapped
+// by move, the state file inside path.Root records them as seen from within it.
+func reroot(state State, move func(string) string) State {
+	moved := State{}
+	for key, pkg := range state {
+		moved[Key{Prefix: move(key.Prefix), Name: key.Name}] = pkg.reroot(move)
+	}
+	return moved
+}
+
+func (pkg Package) reroot(move func(string) string) Package {
+	pkg.Prefix = move(pkg.Prefix)
+	if pkg.Files != nil {
+		files := []string{}
+		for _, file := range pkg.Files {
+			files = append(files, move(file))
+		}
+		pkg.Files = files
+	}
+	if pkg.Checksums != nil {
+		checksums := map[string]string{}
+		for file, checksum := range pkg.Checksums {
+			checksums[move(file)] = checksum
+		}
+		pkg.Checksums = checksums
+	}
+	if pkg.Modes != nil {
+		modes := map[string]os.FileMode{}
+		for file, mode := range pkg.Modes {
+			modes[move(file)] = mode
+		}
+		pkg.Modes = modes
+	}
+	if pkg.Previous != nil {
+		previous := pkg.Previous.reroot(move)
+		pkg.Previous = &previous
+	}
+	return pkg
+}
 
 func statePath() string {
 	return filepath.Join(path.StateDir, "installed.json")
 }
 
-func load() (State, error) {
-	state := make(State)
-	path := statePath()
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return state, nil
+// ErrCorrupt is returned when the state file can not be parsed.
+var ErrCorrupt = errors.New("state file is corrupt")
+
+func corrupt(file string, err error) error {
+	hint := ""
+	if path.Exists(file + ".1") {
+		hint = fmt.Sprintf(", to restore the most recent backup run:\n"+
+			"  cp '%s.1' '%s'", file, file)
+	}
+	return fmt.Errorf("%w: '%s': %v%s", ErrCorrupt, file, err, hint)
+}
+
+func read() (document, error) {
+	doc := newDocument(State{})
+	file := statePath()
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return doc, nil
 	}
-	data, err := os.ReadFile(path)
+	data, err := os.ReadFile(file)
 	if err != nil {
-		return state, err
+		return doc, err
+	}
+
+	header := struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return doc, corrupt(file, err)
 	}
-	err = json.Unmarshal(data, &state)
-	return state, err
+	version := max(header.SchemaVersion, 1)
+	if version > SchemaVersion {
+		return doc, fmt.Errorf("state file '%s' has schema version %d, "+
+			"this version of tuck only supports up to %d", file, version, SchemaVersion)
+	}
+	for ; version < SchemaVersion; version++ {
+		data, err = migrations[version-1](data)
+		if err != nil {
+			return doc, corrupt(file, err)
+		}
+	}
+
+	doc = document{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return doc, corrupt(file, err)
+	}
+	if doc.Packages == nil || doc.Files == nil || path.Root != "" {
+		doc = newDocument(reroot(doc.state(), path.Rooted))
+	}
+	return doc, nil
 }
 
+func load() (State, error) {
+	doc, err := read()
+	return doc.state(), err
+}
+
+// Check reports whether the state file can be loaded.
+func Check() error {
+	_, err := load()
+	return err
+}
+
+// rotate shifts the backups of the state file along by one, discarding the
+// oldest, and makes a copy of the current file the most recent backup.
+func rotate(file string) error {
+	for i := Backups - 1; i > 0; i-- {
+		backup := fmt.Sprintf("%s.%d", file, i)
+		if path.Exists(backup) {
+			if err := os.Rename(backup, fmt.Sprintf("%s.%d", file, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if !path.Exists(file) {
+		return nil
+	}
+	return path.CopyFile(file, file+".1")
+}
+
+// store writes the state to a temporary file which is synced to disk before
+// being renamed over the state file, so that a crash or full disk can never
+// leave a partially written state file.
 func store(state State) error {
-	path := statePath()
-	data, err := json.MarshalIndent(state, "", "  ")
+	file := statePath()
+	data, err := json.MarshalIndent(newDocument(reroot(state, path.Unrooted)), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".installed-*.json")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if err := rotate(file); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+	// sync the directory so the rename is durable, not supported everywhere
+	if dir, err := os.Open(filepath.Dir(file)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
 }
 
+// Install records the package as installed into pkg.Prefix.
 func Install(name string, pkg Package) error {
 	state, err := load()
 	if err != nil {
 		return err
 	}
-	state[name] = pkg
+	state[Key{Prefix: pkg.Prefix, Name: name}] = pkg
 	return store(state)
 }
 
@@ -60,26 +352,112 @@ func GetAll() (*State, error) {
 	return &state, nil
 }
 
-func Get(name string) (*Package, error) {
+// Get returns the package installed into prefix, or nil when it is not.
+func Get(prefix string, name string) (*Package, error) {
 	state, err := load()
 	if err != nil {
 		return nil, err
 	}
-	pkg, found := state[name]
+	pkg, found := state[Key{Prefix: prefix, Name: name}]
 	if !found {
 		return nil, nil
 	}
 	return &pkg, nil
 }
 
-func Remove(name string) error {
+// Lookup finds the package installed into prefix, or into any prefix when
+// prefix is empty as long as it is only installed into one. The package is
+// nil when it is not installed.
+func Lookup(name string, prefix string) (Key, *Package, error) {
 	state, err := load()
 	if err != nil {
-		return err
+		return Key{}, nil, err
+	}
+	if prefix != "" {
+		key := Key{Prefix: prefix, Name: name}
+		pkg, found := state[key]
+		if !found {
+			return key, nil, nil
+		}
+		return key, &pkg, nil
+	}
+	keys := []Key{}
+	prefixes := []string{}
+	for key := range state {
+		if key.Name == name {
+			keys = append(keys, key)
+			prefixes = append(prefixes, path.Contract(key.Prefix))
+		}
 	}
-	_, found := state[name]
-	if found {
-		delete(state, name)
+	switch len(keys) {
+	case 0:
+		return Key{Name: name}, nil, nil
+	case 1:
+		pkg := state[keys[0]]
+		return keys[0], &pkg, nil
+	default:
+		slices.Sort(prefixes)
+		return Key{}, nil, fmt.Errorf("'%s' is %w: %s",
+			name, ErrAmbiguous, strings.Join(prefixes, ", "))
 	}
+}
+
+// Stored reports whether the version of the package is kept in the store from
+// the asset for any prefix, along with the digest of the asset when it is
+// recorded, which is only for the active and previous versions.
+func Stored(name string, version string, asset string) (bool, string, error) {
+	state, err := load()
+	if err != nil {
+		return false, "", err
+	}
+	kept, digest := false, ""
+	for key, pkg := range state {
+		if key.Name != name || !slices.Contains(pkg.Versions, version) ||
+			pkg.Assets[version] != asset {
+			continue
+		}
+		kept = true
+		for _, entry := range []*Package{&pkg, pkg.Previous} {
+			if entry != nil && entry.Version == version && entry.Asset == asset &&
+				entry.Digest != "" {
+				digest = entry.Digest
+			}
+		}
+	}
+	return kept, digest, nil
+}
+
+// StorePath returns the directory in the store holding the version of the
+// package, see path.StorePath.
+func (pkg Package) StorePath(name string, version string) string {
+	return path.StorePath(name, version, pkg.Assets[version])
+}
+
+// ErrAmbiguous is returned by Lookup when a package is installed into more
+// than one prefix and no prefix was given.
+var ErrAmbiguous = errors.New("installed into more than one prefix")
+
+// Remove forgets the package installed into prefix.
+func Remove(prefix string, name string) error {
+	state, err := load()
+	if err != nil {
+		return err
+	}
+	delete(state, Key{Prefix: prefix, Name: name})
 	return store(state)
 }
+
+// Owner returns the key and record of the package which installed file, or a
+// nil package when no package did.
+func Owner(file string) (Key, *Package, error) {
+	doc, err := read()
+	if err != nil {
+		return Key{}, nil, err
+	}
+	key, found := doc.Files[filepath.Clean(file)]
+	if !found {
+		return Key{}, nil, nil
+	}
+	pkg := doc.Packages[key.Prefix][key.Name]
+	return key, &pkg, nil
+}
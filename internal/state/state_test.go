@@ -0,0 +1,263 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"tuck/internal/path"
+)
+
+func setStateDir(t *testing.T) string {
+	dir := t.TempDir()
+	old := path.StateDir
+	path.StateDir = dir
+	t.Cleanup(func() { path.StateDir = old })
+	return filepath.Join(dir, "installed.json")
+}
+
+func TestMigrateV1(t *testing.T) {
+	file := setStateDir(t)
+	legacy := `{"tool": {"prefix": "/p", "release": "v1", "local": false, "files": ["/p/bin/tool"]}}`
+	if err := os.WriteFile(file, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := Get("/p", "tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg == nil || pkg.Release != "v1" || len(pkg.Files) != 1 {
+		t.Fatalf("unexpected package after migration: %+v", pkg)
+	}
+
+	if err := Install("other", Package{Prefix: "/p"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := document{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion != SchemaVersion || len(doc.Packages["/p"]) != 2 {
+		t.Fatalf("unexpected document written: %s", data)
+	}
+	backup, err := os.ReadFile(file + ".1")
+	if err != nil || string(backup) != legacy {
+		t.Fatalf("expected the legacy file as backup, got %q (%v)", backup, err)
+	}
+}
+
+func TestBackups(t *testing.T) {
+	file := setStateDir(t)
+	for i := range Backups + 2 {
+		if err := Install(fmt.Sprint("pkg", i), Package{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= Backups; i++ {
+		if !path.Exists(fmt.Sprintf("%s.%d", file, i)) {
+			t.Errorf("missing backup %d", i)
+		}
+	}
+	if path.Exists(fmt.Sprintf("%s.%d", file, Backups+1)) {
+		t.Errorf("more than %d backups kept", Backups)
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(file), ".installed-*"))
+	if len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	file := setStateDir(t)
+	if err := Install("tool", Package{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install("other", Package{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(`{"schemaVersion": 2, "packa`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Check()
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected corrupt state error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), file+".1") {
+		t.Errorf("expected a repair hint, got %v", err)
+	}
+	if err := Install("third", Package{}); err == nil {
+		t.Error("expected writing a corrupt state to fail")
+	}
+}
+
+func TestNewerSchema(t *testing.T) {
+	file := setStateDir(t)
+	data := fmt.Sprintf(`{"schemaVersion": %d, "packages": {}}`, SchemaVersion+1)
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(); err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Fatalf("expected schema version error, got %v", err)
+	}
+}
+
+func TestOwner(t *testing.T) {
+	setStateDir(t)
+	tool := Package{Prefix: "/p", Files: []string{"/p/bin/tool", "/p/share/man/man1/tool.1"}}
+	if err := Install("tool", tool); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install("other", Package{Prefix: "/p", Files: []string{"/p/bin/other"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	key, pkg, err := Owner("/p/bin/../bin/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Name != "tool" || key.Prefix != "/p" || pkg == nil || len(pkg.Files) != 2 {
+		t.Errorf("expected tool to own the file, got %+v %+v", key, pkg)
+	}
+
+	// the file belongs to the package which installed it last
+	newer := Package{Prefix: "/p", Files: []string{"/p/bin/tool"}, Installed: time.Now()}
+	if err := Install("newer", newer); err != nil {
+		t.Fatal(err)
+	}
+	if key, _, _ := Owner("/p/bin/tool"); key.Name != "newer" {
+		t.Errorf("expected newer to own the file, got %+v", key)
+	}
+
+	if err := Remove("/p", "newer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove("/p", "tool"); err != nil {
+		t.Fatal(err)
+	}
+	if _, pkg, _ := Owner("/p/bin/tool"); pkg != nil {
+		t.Errorf("expected no owner after removal, got %+v", pkg)
+	}
+	if key, _, _ := Owner("/p/bin/other"); key.Name != "other" {
+		t.Errorf("expected other to own the file, got %+v", key)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	file := setStateDir(t)
+	v3 := `{"schemaVersion": 3, "packages": {"tool": {"prefix": "/a", "release": "v1", "local": false, "files": ["/a/bin/tool"]}},
+		"files": {"/a/bin/tool": "tool"}}`
+	if err := os.WriteFile(file, []byte(v3), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install("tool", Package{Prefix: "/b", Release: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := Get("/a", "tool")
+	if err != nil || pkg == nil || pkg.Release != "v1" {
+		t.Fatalf("expected the migrated package, got %+v (%v)", pkg, err)
+	}
+	if _, _, err := Lookup("tool", ""); !errors.Is(err, ErrAmbiguous) {
+		t.Errorf("expected an ambiguous lookup, got %v", err)
+	}
+	key, pkg, err := Lookup("tool", "/b")
+	if err != nil || pkg == nil || key.Prefix != "/b" || pkg.Release != "v2" {
+		t.Errorf("expected the package in /b, got %+v %+v (%v)", key, pkg, err)
+	}
+
+	if err := Remove("/a", "tool"); err != nil {
+		t.Fatal(err)
+	}
+	key, pkg, err = Lookup("tool", "")
+	if err != nil || pkg == nil || key.Prefix != "/b" {
+		t.Errorf("expected the only remaining package, got %+v %+v (%v)", key, pkg, err)
+	}
+	if _, pkg, _ := Lookup("missing", ""); pkg != nil {
+		t.Errorf("expected no package, got %+v", pkg)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	file := setStateDir(t)
+	path.Root = "/rootfs"
+	t.Cleanup(func() { path.Root = "" })
+
+	err := Install("tool", Package{
+		Prefix:    "/rootfs/usr/local",
+		Files:     []string{"/rootfs/usr/local/bin/tool"},
+		Checksums: map[string]string{"/rootfs/usr/local/bin/tool": "sum"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "/rootfs") {
+		t.Errorf("expected paths as seen from inside the root, got %s", data)
+	}
+
+	key, pkg, err := Owner("/rootfs/usr/local/bin/tool")
+	if err != nil || pkg == nil || key.Prefix != "/rootfs/usr/local" {
+		t.Fatalf("expected the package inside the root, got %+v %+v (%v)", key, pkg, err)
+	}
+	if pkg.Checksums["/rootfs/usr/local/bin/tool"] != "sum" {
+		t.Errorf("expected the checksums inside the root, got %v", pkg.Checksums)
+	}
+}
+
+func TestStored(t *testing.T) {
+	setStateDir(t)
+	glibc := Package{Prefix: "/glibc", Version: "1.0.0", Versions: []string{"1.0.0"},
+		Assets: map[string]string{"1.0.0": "tool-linux-gnu.tar.gz"},
+		Asset:  "tool-linux-gnu.tar.gz", Digest: "sha256:gnu"}
+	if err := Install("tool", glibc); err != nil {
+		t.Fatal(err)
+	}
+	musl := Package{Prefix: "/musl", Version: "2.0.0", Versions: []string{"1.0.0", "2.0.0"},
+		Assets: map[string]string{
+			"1.0.0": "tool-linux-musl.tar.gz",
+			"2.0.0": "tool-linux-musl.tar.gz",
+		},
+		Asset:    "tool-linux-musl.tar.gz",
+		Previous: &Package{Prefix: "/musl", Version: "1.0.0", Asset: "tool-linux-musl.tar.gz"}}
+	if err := Install("tool", musl); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		version, asset, digest string
+		kept                   bool
+	}{
+		{"1.0.0", "tool-linux-gnu.tar.gz", "sha256:gnu", true},
+		// the digest of the previous version was not recorded
+		{"1.0.0", "tool-linux-musl.tar.gz", "", true},
+		{"2.0.0", "tool-linux-gnu.tar.gz", "", false},
+		{"3.0.0", "tool-linux-musl.tar.gz", "", false},
+	} {
+		kept, digest, err := Stored("tool", test.version, test.asset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept != test.kept || digest != test.digest {
+			t.Errorf("%s %s: expected %v %q, got %v %q", test.version, test.asset,
+				test.kept, test.digest, kept, digest)
+		}
+	}
+
+	if dir := musl.StorePath("tool", "1.0.0"); dir != filepath.Join(path.StoreDir,
+		"tool", "1.0.0", "tool-linux-musl.tar.gz") {
+		t.Errorf("unexpected store directory %s", dir)
+	}
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"os"
+	"tuck/internal/path"
+)
+
+// ProblemKind is a way an installed file differs from its record.
+type ProblemKind string
+
+const (
+	Missing     ProblemKind = "missing"
+	Modified    ProblemKind = "modified"
+	Permissions ProblemKind = "permissions changed"
+	TypeChanged ProblemKind = "type changed"
+	BrokenLink  ProblemKind = "broken symlink"
+)
+
+// Problem is an installed file which does not match the package record.
+type Problem struct {
+	File string
+	Kind ProblemKind
+}
+
+// Verify compares the files of the package on disk with the checksums and
+// modes recorded when they were installed. Packages installed before modes
+// were recorded are only checked for missing, broken and modified files.
+func Verify(pkg Package) ([]Problem, error) {
+	problems := []Problem{}
+	for _, file := range pkg.Files {
+		info, err := os.Lstat(file)
+		if os.IsNotExist(err) {
+			problems = append(problems, Problem{file, Missing})
+			continue
+		}
+		if err != nil {
+			return problems, err
+		}
+
+		recorded, hasMode := pkg.Modes[file]
+		if hasMode && recorded.Type() != info.Mode().Type() {
+			problems = append(problems, Problem{file, TypeChanged})
+			continue
+		}
+		mode, err := path.FileMode(file)
+		if os.IsNotExist(err) {
+			problems = append(problems, Problem{file, BrokenLink})
+			continue
+		}
+		if err != nil {
+			return problems, err
+		}
+		if hasMode && recorded.Perm() != mode.Perm() {
+			problems = append(problems, Problem{file, Permissions})
+		}
+
+		checksum, hasChecksum := pkg.Checksums[file]
+		if !hasChecksum || mode.IsDir() {
+			continue
+		}
+		current, err := path.HashFile(file)
+		if err != nil {
+			return problems, err
+		}
+		if current != checksum {
+			problems = append(problems, Problem{file, Modified})
+		}
+	}
+	return problems, nil
+}
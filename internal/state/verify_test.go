@@ -0,0 +1,79 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"tuck/internal/path"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string, mode os.FileMode) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	link := func(name string, target string) string {
+		file := filepath.Join(dir, name)
+		if err := os.Symlink(target, file); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	files := []string{
+		write("ok", "ok", 0755),
+		write("missing", "missing", 0644),
+		write("modified", "original", 0644),
+		write("permissions", "permissions", 0755),
+		write("type", "type", 0644),
+		link("broken", write("target", "target", 0644)),
+		link("link", write("linked", "linked", 0755)),
+	}
+	pkg := Package{Files: files, Checksums: map[string]string{}, Modes: map[string]os.FileMode{}}
+	for _, file := range files {
+		pkg.Checksums[file], _ = path.HashFile(file)
+		pkg.Modes[file], _ = path.FileMode(file)
+	}
+
+	problems, err := Verify(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("expected no problems, got %+v", problems)
+	}
+
+	os.Remove(filepath.Join(dir, "missing"))
+	write("modified", "changed", 0644)
+	os.Chmod(filepath.Join(dir, "permissions"), 0644)
+	os.Remove(filepath.Join(dir, "type"))
+	link("type", filepath.Join(dir, "linked"))
+	os.Remove(filepath.Join(dir, "target"))
+
+	problems, err = Verify(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Problem{
+		{filepath.Join(dir, "missing"), Missing},
+		{filepath.Join(dir, "modified"), Modified},
+		{filepath.Join(dir, "permissions"), Permissions},
+		{filepath.Join(dir, "type"), TypeChanged},
+		{filepath.Join(dir, "broken"), BrokenLink},
+	}
+	if !slices.Equal(problems, expected) {
+		t.Errorf("expected %+v, got %+v", expected, problems)
+	}
+
+	// without recorded modes only the content is checked
+	pkg.Modes = nil
+	problems, _ = Verify(pkg)
+	if len(problems) != 4 {
+		t.Errorf("expected 4 problems without modes, got %+v", problems)
+	}
+}
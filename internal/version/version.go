@@ -0,0 +1,222 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a loosely parsed version string, such as "v1.2.3", "1.22rc1" or
+// "2.0.0-beta.1". Leading numeric components are compared numerically,
+// anything following them is treated as a pre-release suffix.
+type Version struct {
+	Original string
+	Parts    []int
+	Pre      string
+}
+
+func Parse(s string) (Version, error) {
+	version := Version{Original: s}
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	for {
+		end := 0
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		part, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			return version, err
+		}
+		version.Parts = append(version.Parts, part)
+		rest = rest[end:]
+		if !strings.HasPrefix(rest, ".") || len(rest) < 2 ||
+			rest[1] < '0' || rest[1] > '9' {
+			break
+		}
+		rest = rest[1:]
+	}
+	if len(version.Parts) == 0 {
+		return version, fmt.Errorf("invalid version: '%s'", s)
+	}
+	// build metadata never affects precedence
+	rest, _, _ = strings.Cut(rest, "+")
+	version.Pre = strings.TrimLeft(rest, "-.")
+	return version, nil
+}
+
+func (v Version) String() string {
+	return v.Original
+}
+
+// Prerelease reports whether the version has a pre-release suffix.
+func (v Version) Prerelease() bool {
+	return v.Pre != ""
+}
+
+// Compare returns -1, 0 or 1 when a is less than, equal to or greater than b.
+// Missing components are treated as zero and a pre-release is less than the
+// same version without one.
+func Compare(a Version, b Version) int {
+	for i := 0; i < max(len(a.Parts), len(b.Parts)); i++ {
+		x, y := 0, 0
+		if i < len(a.Parts) {
+			x = a.Parts[i]
+		}
+		if i < len(b.Parts) {
+			y = b.Parts[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case a.Pre == b.Pre:
+		return 0
+	case a.Pre == "":
+		return 1
+	case b.Pre == "":
+		return -1
+	default:
+		return strings.Compare(a.Pre, b.Pre)
+	}
+}
+
+type clause struct {
+	op      string
+	version Version
+}
+
+// Constraint is a comma separated list of clauses which must all be satisfied,
+// each clause is an operator followed by a version:
+//
+//	>=1.2, <2   range of versions
+//	=1.2.3      exact version
+//	1.2         any 1.2.x version
+//	~1.2.3      any 1.2.x version from 1.2.3
+//	^1.2.3      any 1.x.y version from 1.2.3
+//
+// An empty constraint, or "*", is satisfied by every version.
+type Constraint []clause
+
+func ParseConstraint(s string) (Constraint, error) {
+	constraint := Constraint{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || field == "*" {
+			continue
+		}
+		op := ""
+		for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+			if strings.HasPrefix(field, candidate) {
+				op = candidate
+				break
+			}
+		}
+		version, err := Parse(strings.TrimSpace(field[len(op):]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint '%s': %w", s, err)
+		}
+		constraint = append(constraint, clause{op: op, version: version})
+	}
+	return constraint, nil
+}
+
+// hasPrefix reports whether the components of prefix match the leading
+// components of v.
+func hasPrefix(v Version, prefix Version, n int) bool {
+	if len(v.Parts) < n {
+		return false
+	}
+	for i := range n {
+		if v.Parts[i] != prefix.Parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c clause) check(v Version) bool {
+	cmp := Compare(v, c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "=":
+		return cmp == 0
+	case "~":
+		return cmp >= 0 && hasPrefix(v, c.version, min(2, len(c.version.Parts)))
+	case "^":
+		return cmp >= 0 && hasPrefix(v, c.version, 1)
+	default:
+		if c.version.Pre != "" {
+			return cmp == 0
+		}
+		return hasPrefix(v, c.version, len(c.version.Parts))
+	}
+}
+
+// Check reports whether v satisfies every clause of the constraint.
+// Pre-releases only satisfy a constraint which explicitly mentions a
+// pre-release.
+func (c Constraint) Check(v Version) bool {
+	if v.Prerelease() {
+		allowed := false
+		for _, clause := range c {
+			if clause.version.Prerelease() {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	for _, clause := range c {
+		if !clause.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	clauses := []string{}
+	for _, clause := range c {
+		clauses = append(clauses, clause.op+clause.version.String())
+	}
+	return strings.Join(clauses, ", ")
+}
+
+// Latest returns the greatest of candidates which satisfies the constraint,
+// candidates which are not versions are ignored.
+func Latest(candidates []string, constraint Constraint) (string, error) {
+	var latest *Version
+	for _, candidate := range candidates {
+		version, err := Parse(candidate)
+		if err != nil || !constraint.Check(version) {
+			continue
+		}
+		if latest == nil || Compare(version, *latest) > 0 {
+			latest = &version
+		}
+	}
+	if latest == nil {
+		if len(constraint) > 0 {
+			return "", fmt.Errorf("no version satisfies '%s'", constraint)
+		}
+		return "", fmt.Errorf("no versions found")
+	}
+	return latest.Original, nil
+}
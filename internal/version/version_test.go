@@ -0,0 +1,67 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"1.2.3", "1.2.4", -1},
+		{"2.0.0-rc.1", "2.0.0", -1},
+		{"1.22rc1", "1.22", -1},
+		{"1.2.3+build", "1.2.3", 0},
+	}
+	for _, test := range tests {
+		a, err := Parse(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := Parse(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Compare(a, b); got != test.want {
+			t.Errorf("Compare(%q, %q) = %d, expected %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	candidates := []string{"v1.2.0", "v1.2.5", "v1.3.0", "v2.0.0-rc.1", "v2.0.0", "latest"}
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"", "v2.0.0"},
+		{"*", "v2.0.0"},
+		{"<2", "v1.3.0"},
+		{">=1.2, <1.3", "v1.2.5"},
+		{"1.2", "v1.2.5"},
+		{"~1.2.1", "v1.2.5"},
+		{"^1.2", "v1.3.0"},
+		{"=1.2.0", "v1.2.0"},
+		{">=2.0.0-rc.1, <2", "v2.0.0-rc.1"},
+	}
+	for _, test := range tests {
+		constraint, err := ParseConstraint(test.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Latest(candidates, constraint)
+		if err != nil {
+			t.Errorf("Latest(%q) failed: %v", test.constraint, err)
+		} else if got != test.want {
+			t.Errorf("Latest(%q) = %q, expected %q", test.constraint, got, test.want)
+		}
+	}
+
+	constraint, _ := ParseConstraint(">3")
+	if _, err := Latest(candidates, constraint); err == nil {
+		t.Error("Latest(\">3\") should have failed")
+	}
+}